@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+	"github.com/vieitesss/k8s-d2/pkg/render"
 )
 
 var diagramCmd = &cobra.Command{
 	Use:   "diagram",
-	Short: "Generate D2 diagrams from Kubernetes cluster topology",
+	Short: "Generate diagrams from Kubernetes cluster topology",
 	Long: `Generate D2 diagram files visualizing namespaces, workloads,
-services, and their relationships from your Kubernetes cluster.`,
+services, and their relationships from your Kubernetes cluster.
+Use --format to produce another diagram format instead of D2.
+
+Use --from-file, --helm or --kustomize to diagram manifests before they are
+applied, or --from-snapshot to diagram a snapshot saved with k8sdd snapshot;
+no cluster access is needed in either case. Manifest inputs can be combined
+and are merged into a single topology.
+
+Use --watch to keep a diagram of the live cluster up to date: the output file
+is rewritten whenever a watched resource changes, using informers instead of
+polling the API server.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	RunE:          runGenerate,
@@ -22,6 +36,21 @@ func init() {
 	diagramCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
 	diagramCmd.Flags().StringVarP(&rootOptions.output, "output", "o", "", "output file (default: stdout)")
 	diagramCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "include PVC/StorageClass layer")
+	diagramCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "include NetworkPolicy layer")
+	diagramCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "include PodDisruptionBudgets and drain-safety warnings")
+	diagramCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "include workload references to ConfigMaps and Secrets")
+	diagramCmd.Flags().BoolVar(&rootOptions.includeEPs, "include-endpoints", false, "wire services from EndpointSlices, showing endpoint readiness")
+	diagramCmd.Flags().StringVar(&rootOptions.detail, "detail", detailWorkloads, "level of detail: workloads, or pods to show ReplicaSets and Pods")
+	diagramCmd.Flags().StringVar(&rootOptions.view, "view", string(render.ViewNamespaces), "grouping: namespaces, nodes or zones to place workloads where their pods run")
 	diagramCmd.Flags().IntVar(&rootOptions.gridColumns, "grid-columns", 3, "number of columns in grid layout (0 for single column)")
 	diagramCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
+	diagramCmd.Flags().StringArrayVarP(&rootOptions.fromFiles, "from-file", "f", nil, "manifest file or directory to diagram instead of a live cluster (repeatable, - for stdin)")
+	diagramCmd.Flags().StringVar(&rootOptions.helmChart, "helm", "", "Helm chart (directory, archive or repo reference) to template and diagram")
+	diagramCmd.Flags().StringArrayVar(&rootOptions.helmValues, "values", nil, "values file for --helm (repeatable)")
+	diagramCmd.Flags().StringVar(&rootOptions.releaseName, "release-name", manifest.DefaultReleaseName, "release name for --helm")
+	diagramCmd.Flags().StringVar(&rootOptions.fromSnapshot, "from-snapshot", "", "JSON snapshot (from k8sdd snapshot) to diagram instead of a live cluster")
+	diagramCmd.Flags().StringVar(&rootOptions.format, "format", string(render.FormatD2), "output format ("+render.FormatList()+")")
+	diagramCmd.Flags().BoolVarP(&rootOptions.watch, "watch", "w", false, "rewrite the output file whenever the cluster changes (requires --output)")
+	diagramCmd.Flags().DurationVar(&rootOptions.debounce, "debounce", 2*time.Second, "with --watch, wait this long after a change before rewriting")
+	diagramCmd.Flags().StringVarP(&rootOptions.kustomizeDir, "kustomize", "k", "", "kustomization directory to build and diagram")
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/diff"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Generate a D2 diagram of the differences between two topologies",
+	Long: `Compare two topologies and generate a D2 diagram of the merged result:
+added resources are green, removed ones red and changed ones (replicas,
+service type, ports, selectors, mounts) amber.
+
+` + sourceHelp + `
+
+Examples:
+  k8sdd diff context:staging context:prod -o drift.d2
+  k8sdd diff snapshot:yesterday.json cluster
+  k8sdd diff cluster file:deploy/`,
+	Args:          cobra.ExactArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&rootOptions.kubeconfig, "kubeconfig", "", "path to kubeconfig (default: ~/.kube/config)")
+	diffCmd.Flags().StringVarP(&rootOptions.namespace, "namespace", "n", "", "namespace to compare (default: all non-system)")
+	diffCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
+	diffCmd.Flags().StringVarP(&rootOptions.output, "output", "o", "", "output file (default: stdout)")
+	diffCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "include PVC/StorageClass layer")
+	diffCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "include NetworkPolicy layer")
+	diffCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "include PodDisruptionBudgets and drain-safety warnings")
+	diffCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "include workload references to ConfigMaps and Secrets")
+	diffCmd.Flags().BoolVar(&rootOptions.includeEPs, "include-endpoints", false, "wire services from EndpointSlices, showing endpoint readiness")
+	diffCmd.Flags().StringArrayVar(&rootOptions.helmValues, "values", nil, "values file for helm: sources (repeatable)")
+	diffCmd.Flags().StringVar(&rootOptions.releaseName, "release-name", manifest.DefaultReleaseName, "release name for helm: sources")
+	diffCmd.Flags().StringVar(&rootOptions.detail, "detail", detailWorkloads, "level of detail: workloads, or pods to show ReplicaSets and Pods")
+	diffCmd.Flags().IntVar(&rootOptions.gridColumns, "grid-columns", 3, "number of columns in grid layout (0 for single column)")
+	diffCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	configureLogger()
+
+	oldSource, err := parseSource(args[0])
+	if err != nil {
+		return err
+	}
+	newSource, err := parseSource(args[1])
+	if err != nil {
+		return err
+	}
+
+	oldCluster, err := oldSource.load(cmd.Context())
+	if err != nil {
+		return err
+	}
+	newCluster, err := newSource.load(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	result := diff.Compare(oldCluster, newCluster)
+
+	w, closeWriter, err := getOutputWriter()
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+
+	if err := diff.NewD2Renderer(w, rootOptions.gridColumns).Render(result); err != nil {
+		return err
+	}
+
+	if !result.HasChanges() {
+		log.Info("Topologies are identical", "old", oldSource, "new", newSource)
+		return nil
+	}
+	log.Info("Diff diagram generated successfully", "old", oldSource, "new", newSource)
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/diff"
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// Report formats of the drift command.
+const (
+	reportText = "text"
+	reportJSON = "json"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift --expected <source>",
+	Short: "Compare the live cluster against an expected topology",
+	Long: `Fetch the live cluster topology and compare it against an expected one,
+listing missing, extra and mismatched workloads, services and PVCs.
+
+Only namespaces present in the expected topology are compared, and unless
+--namespace or --all-namespaces is given only those are fetched, system
+namespaces such as "default" included.
+
+The expected topology is a source as accepted by 'k8sdd diff', usually a
+manifest directory (file:deploy/) or a snapshot (snapshot:prod.json). A
+Helm chart (helm:<chart>) is templated with the --values files and
+--release-name of the deployed release.
+
+Exit codes: 0 when there is no drift, 1 when drift is detected, 2 on error.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runDrift,
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVar(&rootOptions.expected, "expected", "", "expected topology: manifests, chart or snapshot (required)")
+	driftCmd.Flags().StringVar(&rootOptions.kubeconfig, "kubeconfig", "", "path to kubeconfig (default: ~/.kube/config)")
+	driftCmd.Flags().StringVar(&rootOptions.kubeContext, "context", "", "kubeconfig context of the live cluster (default: current)")
+	driftCmd.Flags().StringVarP(&rootOptions.namespace, "namespace", "n", "", "namespace to compare (default: all non-system)")
+	driftCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
+	driftCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "compare PVCs too")
+	driftCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "compare NetworkPolicies too")
+	driftCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "compare PodDisruptionBudgets too")
+	driftCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "compare ConfigMap and Secret references too")
+	driftCmd.Flags().StringArrayVar(&rootOptions.helmValues, "values", nil, "values file for a helm: expected topology (repeatable)")
+	driftCmd.Flags().StringVar(&rootOptions.releaseName, "release-name", manifest.DefaultReleaseName, "release name for a helm: expected topology")
+	driftCmd.Flags().StringVar(&rootOptions.reportFormat, "format", reportText, "report format: text or json")
+	driftCmd.Flags().StringVarP(&rootOptions.output, "output", "o", "", "output file (default: stdout)")
+	driftCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
+
+	_ = driftCmd.MarkFlagRequired("expected")
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	report, err := detectDrift(cmd)
+	if err != nil {
+		return &ExitError{Code: exitError, Err: err}
+	}
+
+	if report.HasDrift() {
+		return &ExitError{Code: exitDrift, Err: fmt.Errorf("drift detected: %s", report.Summary())}
+	}
+
+	log.Info("No drift detected")
+	return nil
+}
+
+func detectDrift(cmd *cobra.Command) (*diff.DriftReport, error) {
+	configureLogger()
+
+	if rootOptions.reportFormat != reportText && rootOptions.reportFormat != reportJSON {
+		return nil, fmt.Errorf("unknown report format %q (expected %s or %s)", rootOptions.reportFormat, reportText, reportJSON)
+	}
+
+	source, err := parseSource(rootOptions.expected)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := source.load(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := createClientWithSpinner(rootOptions.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := fetchTopologyWithSpinner(cmd.Context(), client, driftFetchOptions(expected))
+	if err != nil {
+		return nil, err
+	}
+
+	report := diff.Drift(expected, live)
+
+	w, closeWriter, err := getOutputWriter()
+	if err != nil {
+		return nil, err
+	}
+	defer closeWriter()
+
+	if rootOptions.reportFormat == reportJSON {
+		err = report.WriteJSON(w)
+	} else {
+		err = report.WriteText(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// driftFetchOptions scopes the live fetch to the expected namespaces when
+// no namespace flag is given. Manifests without metadata.namespace land in
+// "default", which the live fetch would otherwise skip as a system
+// namespace, reporting every expected resource as missing.
+func driftFetchOptions(expected *model.Cluster) kube.FetchOptions {
+	opts := fetchOptions()
+	if opts.Namespace == "" && !opts.AllNamespaces {
+		opts.Namespaces = expected.NamespaceNames()
+	}
+	return opts
+}
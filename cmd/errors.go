@@ -0,0 +1,22 @@
+package cmd
+
+// Exit codes of the drift command, following diff(1): 0 when the topologies
+// match, 1 when they differ and 2 when the comparison itself failed.
+const (
+	exitDrift = 1
+	exitError = 2
+)
+
+// ExitError is an error that asks the process to exit with a specific code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -2,36 +2,32 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/charmbracelet/huh/spinner"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
 	"github.com/vieitesss/k8s-d2/pkg/model"
 	"github.com/vieitesss/k8s-d2/pkg/render"
 )
 
 func runGenerate(cmd *cobra.Command, args []string) error {
-	log.SetReportTimestamp(false)
-
-	// Configure logger for quiet mode - suppress INFO but keep WARN/ERROR
-	if rootOptions.quiet {
-		log.SetLevel(log.WarnLevel)
+	if rootOptions.watch {
+		return runWatch(cmd)
 	}
 
-	client, err := createClientWithSpinner()
+	configureLogger()
+
+	format, err := outputFormat()
 	if err != nil {
 		return err
 	}
 
-	opts := kube.FetchOptions{
-		Namespace:      rootOptions.namespace,
-		AllNamespaces:  rootOptions.allNamespaces,
-		IncludeStorage: rootOptions.includeStorage,
-	}
-
-	cluster, err := fetchTopologyWithSpinner(cmd.Context(), client, opts)
+	cluster, err := loadTopology(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -42,27 +38,224 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 	defer closeWriter()
 
-	if err := renderWithSpinner(cluster, w); err != nil {
+	if err := renderWithSpinner(cluster, w, format); err != nil {
 		return err
 	}
 
-	log.Info("D2 diagram generated successfully")
+	log.Info("Diagram generated successfully", "format", format)
 	return nil
 }
 
-func createClientWithSpinner() (*kube.Client, error) {
+func configureLogger() {
+	log.SetReportTimestamp(false)
+
+	// Configure logger for quiet mode - suppress INFO but keep WARN/ERROR
+	if rootOptions.quiet {
+		log.SetLevel(log.WarnLevel)
+	}
+}
+
+// outputFormat returns the format selected with --format. The deprecated
+// root command has no --format flag and always renders D2.
+func outputFormat() (render.Format, error) {
+	if rootOptions.format == "" {
+		return render.FormatD2, nil
+	}
+	return render.ParseFormat(rootOptions.format)
+}
+
+// renderOptions returns the renderer options selected with --grid-columns
+// and --view.
+func renderOptions() render.Options {
+	return render.Options{GridColumns: rootOptions.gridColumns, View: render.View(strings.ToLower(rootOptions.view))}
+}
+
+// loadTopology builds the cluster model from a snapshot when --from-snapshot
+// is given, from manifests when --from-file, --helm or --kustomize is given,
+// and from the live cluster otherwise.
+func loadTopology(ctx context.Context) (*model.Cluster, error) {
+	if rootOptions.fromSnapshot != "" {
+		if hasManifestInput() {
+			return nil, fmt.Errorf("--from-snapshot cannot be combined with --from-file, --helm or --kustomize")
+		}
+		return loadSnapshot(rootOptions.fromSnapshot)
+	}
+
+	if hasManifestInput() {
+		return loadManifestsWithSpinner(ctx)
+	}
+
+	return fetchLiveTopology(ctx, "")
+}
+
+// fetchLiveTopology fetches the topology from a kubeconfig context, or the
+// current context when contextName is empty.
+func fetchLiveTopology(ctx context.Context, contextName string) (*model.Cluster, error) {
+	client, err := createClientWithSpinner(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchTopologyWithSpinner(ctx, client, fetchOptions())
+}
+
+func fetchOptions() kube.FetchOptions {
+	return kube.FetchOptions{
+		Namespace:              rootOptions.namespace,
+		AllNamespaces:          rootOptions.allNamespaces,
+		IncludeStorage:         rootOptions.includeStorage,
+		IncludeNetworkPolicies: rootOptions.includeNetPols,
+		IncludePDBs:            rootOptions.includePDBs,
+		IncludeConfigRefs:      rootOptions.includeConfig,
+		IncludeEndpoints:       rootOptions.includeEPs,
+		IncludePods:            includePods(),
+		IncludeNodes:           viewNodes(),
+	}
+}
+
+// includePods reports whether pods must be collected: in pod detail mode,
+// and for the node and zone views, which place workloads by their pods.
+func includePods() bool {
+	return rootOptions.detail == detailPods || viewNodes()
+}
+
+// viewNodes reports whether the view selected with --view needs nodes.
+func viewNodes() bool {
+	return renderOptions().View.NeedsNodes()
+}
+
+func hasManifestInput() bool {
+	return len(rootOptions.fromFiles) > 0 || rootOptions.helmChart != "" || rootOptions.kustomizeDir != ""
+}
+
+func loadManifestsWithSpinner(ctx context.Context) (*model.Cluster, error) {
+	var cluster *model.Cluster
+	var loadErr error
+
+	if rootOptions.quiet {
+		cluster, loadErr = loadManifests(ctx)
+		return cluster, loadErr
+	}
+
+	spinnerErr := spinner.New().
+		Title("Loading manifests...").
+		Action(func() {
+			cluster, loadErr = loadManifests(ctx)
+		}).
+		Run()
+
+	if spinnerErr != nil {
+		return nil, spinnerErr
+	}
+	return cluster, loadErr
+}
+
+// loadManifests merges every manifest input into a single topology.
+func loadManifests(ctx context.Context) (*model.Cluster, error) {
+	loader := newManifestLoader()
+
+	if err := loader.LoadPaths(rootOptions.fromFiles, os.Stdin); err != nil {
+		return nil, err
+	}
+
+	if rootOptions.helmChart != "" {
+		chart := manifest.HelmChart{
+			Chart:       rootOptions.helmChart,
+			ValuesFiles: rootOptions.helmValues,
+			ReleaseName: rootOptions.releaseName,
+		}
+		if err := loader.LoadHelmChart(ctx, chart); err != nil {
+			return nil, err
+		}
+	}
+
+	if rootOptions.kustomizeDir != "" {
+		if err := loader.LoadKustomization(rootOptions.kustomizeDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loader.Err(); err != nil {
+		return nil, err
+	}
+	return loader.Cluster(), nil
+}
+
+// newManifestLoader returns a loader applying the namespace, layer and
+// detail flags, like fetchOptions for a live fetch.
+func newManifestLoader() *manifest.Loader {
+	return manifest.NewLoader(manifest.Options{
+		Namespace:              rootOptions.namespace,
+		IncludeStorage:         rootOptions.includeStorage,
+		IncludeNetworkPolicies: rootOptions.includeNetPols,
+		IncludePDBs:            rootOptions.includePDBs,
+		IncludeConfigRefs:      rootOptions.includeConfig,
+		IncludeEndpoints:       rootOptions.includeEPs,
+		IncludePods:            includePods(),
+		IncludeNodes:           viewNodes(),
+	})
+}
+
+// loadSnapshot reads a JSON snapshot and applies the same filters as a
+// live fetch, so a snapshot renders like the cluster it was taken from.
+func loadSnapshot(path string) (*model.Cluster, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	cluster, err := model.ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var namespaces []model.Namespace
+	for _, ns := range cluster.Namespaces {
+		if rootOptions.namespace != "" && ns.Name != rootOptions.namespace {
+			continue
+		}
+		if !rootOptions.includeStorage {
+			ns.PVCs = nil
+		}
+		if !rootOptions.includeNetPols {
+			ns.ClearNetworkPolicies()
+		}
+		if !rootOptions.includePDBs {
+			ns.PDBs = nil
+		}
+		if !rootOptions.includeConfig {
+			ns.ClearConfigRefs()
+		}
+		if !rootOptions.includeEPs {
+			ns.ClearEndpoints()
+		}
+		if !includePods() {
+			ns.ClearPods()
+		}
+		namespaces = append(namespaces, ns)
+	}
+	cluster.Namespaces = namespaces
+	if !viewNodes() {
+		cluster.Nodes = nil
+	}
+
+	return cluster, nil
+}
+
+func createClientWithSpinner(contextName string) (*kube.Client, error) {
 	var client *kube.Client
 	var clientErr error
 
 	if rootOptions.quiet {
-		client, clientErr = kube.NewClient(rootOptions.kubeconfig)
+		client, clientErr = kube.NewClientForContext(rootOptions.kubeconfig, contextName)
 		return client, clientErr
 	}
 
 	spinnerErr := spinner.New().
 		Title("Creating K8s client...").
 		Action(func() {
-			client, clientErr = kube.NewClient(rootOptions.kubeconfig)
+			client, clientErr = kube.NewClientForContext(rootOptions.kubeconfig, contextName)
 		}).
 		Run()
 
@@ -110,19 +303,22 @@ func getOutputWriter() (*os.File, func(), error) {
 	return f, func() { _ = f.Close() }, nil
 }
 
-func renderWithSpinner(cluster *model.Cluster, w *os.File) error {
+func renderWithSpinner(cluster *model.Cluster, w *os.File, format render.Format) error {
+	renderer, err := render.New(format, w, renderOptions())
+	if err != nil {
+		return err
+	}
+
 	var renderErr error
 
 	if rootOptions.quiet {
-		renderer := render.NewD2Renderer(w, rootOptions.gridColumns)
 		renderErr = renderer.Render(cluster)
 		return renderErr
 	}
 
 	spinnerErr := spinner.New().
-		Title("Rendering D2 diagram...").
+		Title(fmt.Sprintf("Rendering %s diagram...", format)).
 		Action(func() {
-			renderer := render.NewD2Renderer(w, rootOptions.gridColumns)
 			renderErr = renderer.Render(cluster)
 		}).
 		Run()
@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/render"
 )
 
 type RootOptions struct {
@@ -11,9 +16,29 @@ type RootOptions struct {
 	allNamespaces  bool
 	output         string
 	includeStorage bool
+	includeNetPols bool
+	includePDBs    bool
+	includeConfig  bool
+	includeEPs     bool
+	detail         string
+	view           string
 	gridColumns    int
 	showVersion    bool
 	quiet          bool
+	fromFiles      []string
+	helmChart      string
+	helmValues     []string
+	releaseName    string
+	kustomizeDir   string
+	format         string
+	fromSnapshot   string
+	kubeContext    string
+	expected       string
+	reportFormat   string
+	watch          bool
+	debounce       time.Duration
+	addr           string
+	refresh        time.Duration
 }
 
 var rootOptions RootOptions
@@ -23,9 +48,32 @@ var rootCmd = &cobra.Command{
 	Short: "Generate D2 diagrams from Kubernetes cluster topology",
 	Long: `k8s-d2 queries your Kubernetes cluster and generates D2 diagram files
 visualizing namespaces, workloads, services, and their relationships.`,
-	SilenceUsage:  true,
-	SilenceErrors: true,
-	RunE:          runRoot,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	PersistentPreRunE: checkOptions,
+	RunE:              runRoot,
+}
+
+// Detail levels of --detail.
+const (
+	detailWorkloads = "workloads"
+	detailPods      = "pods"
+)
+
+// checkOptions rejects unknown --detail levels and --view names before any
+// command runs. Commands without the flags leave them empty.
+func checkOptions(cmd *cobra.Command, args []string) error {
+	switch rootOptions.detail {
+	case "", detailWorkloads, detailPods:
+	default:
+		return fmt.Errorf("unknown detail level %q (expected %s or %s)", rootOptions.detail, detailWorkloads, detailPods)
+	}
+	if rootOptions.view != "" {
+		if _, err := render.ParseView(rootOptions.view); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
@@ -39,6 +87,12 @@ func init() {
 	rootCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
 	rootCmd.Flags().StringVarP(&rootOptions.output, "output", "o", "", "output file (default: stdout)")
 	rootCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "include PVC/StorageClass layer")
+	rootCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "include NetworkPolicy layer")
+	rootCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "include PodDisruptionBudgets and drain-safety warnings")
+	rootCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "include workload references to ConfigMaps and Secrets")
+	rootCmd.Flags().BoolVar(&rootOptions.includeEPs, "include-endpoints", false, "wire services from EndpointSlices, showing endpoint readiness")
+	rootCmd.Flags().StringVar(&rootOptions.detail, "detail", detailWorkloads, "level of detail: workloads, or pods to show ReplicaSets and Pods")
+	rootCmd.Flags().StringVar(&rootOptions.view, "view", string(render.ViewNamespaces), "grouping: namespaces, nodes or zones to place workloads where their pods run")
 	rootCmd.Flags().IntVar(&rootOptions.gridColumns, "grid-columns", 3, "number of columns in grid layout (0 for single column)")
 	rootCmd.Flags().BoolVarP(&rootOptions.showVersion, "version", "v", false, "show version information")
 	rootCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
@@ -46,5 +100,12 @@ func init() {
 
 func Execute(version string) error {
 	rootCmd.Version = version
-	return rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	// Drift exits with 1 on drift, so its flag, argument and option errors
+	// must not fall through to the generic exit code 1
+	var exitErr *ExitError
+	if err != nil && cmd == driftCmd && !errors.As(err, &exitErr) {
+		return &ExitError{Code: exitError, Err: err}
+	}
+	return err
 }
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+	"github.com/vieitesss/k8s-d2/pkg/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a live diagram of the cluster over HTTP",
+	Long: `Serve the cluster topology over HTTP:
+
+  /              auto-refreshing HTML page with the rendered diagram
+  /diagram.svg   SVG rendered by the d2 CLI, which must be on PATH
+  /diagram.d2    D2 source
+  /topology.json versioned JSON topology
+  /healthz       readiness of the topology cache
+
+The topology is cached and kept up to date with informers, or re-fetched
+every --refresh interval when one is given. Inside a pod the service
+account is used when no kubeconfig is available.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&rootOptions.addr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().DurationVar(&rootOptions.refresh, "refresh", 0, "re-fetch the topology at this interval instead of using informers")
+	serveCmd.Flags().StringVar(&rootOptions.kubeconfig, "kubeconfig", "", "path to kubeconfig (default: ~/.kube/config)")
+	serveCmd.Flags().StringVarP(&rootOptions.namespace, "namespace", "n", "", "namespace to visualize (default: all non-system)")
+	serveCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
+	serveCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "include PVC/StorageClass layer")
+	serveCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "include NetworkPolicy layer")
+	serveCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "include PodDisruptionBudgets and drain-safety warnings")
+	serveCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "include workload references to ConfigMaps and Secrets")
+	serveCmd.Flags().BoolVar(&rootOptions.includeEPs, "include-endpoints", false, "wire services from EndpointSlices, showing endpoint readiness")
+	serveCmd.Flags().StringVar(&rootOptions.detail, "detail", detailWorkloads, "level of detail: workloads, or pods to show ReplicaSets and Pods")
+	serveCmd.Flags().StringVar(&rootOptions.view, "view", string(render.ViewNamespaces), "grouping: namespaces, nodes or zones to place workloads where their pods run")
+	serveCmd.Flags().IntVar(&rootOptions.gridColumns, "grid-columns", 3, "number of columns in grid layout (0 for single column)")
+	serveCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	configureLogger()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := createClientWithSpinner("")
+	if err != nil {
+		return err
+	}
+
+	srv := server.New(server.Options{GridColumns: rootOptions.gridColumns, View: renderOptions().View})
+	if err := startRefresh(ctx, client, srv); err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              rootOptions.addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Serving cluster diagram", "addr", rootOptions.addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// startRefresh loads the first topology and keeps the server cache up to
+// date in the background, by polling with --refresh or through informers.
+func startRefresh(ctx context.Context, client *kube.Client, srv *server.Server) error {
+	opts := fetchOptions()
+
+	if rootOptions.refresh > 0 {
+		cluster, err := fetchTopologyWithSpinner(ctx, client, opts)
+		if err != nil {
+			return err
+		}
+		srv.Update(cluster)
+
+		go srv.Poll(ctx, rootOptions.refresh, func(ctx context.Context) (*model.Cluster, error) {
+			return client.FetchTopology(ctx, opts)
+		})
+		return nil
+	}
+
+	watcher, err := client.NewWatcher(opts)
+	if err != nil {
+		return err
+	}
+	if err := startWatcherWithSpinner(ctx, watcher); err != nil {
+		return err
+	}
+
+	cluster, err := watcher.Topology(ctx)
+	if err != nil {
+		return err
+	}
+	srv.Update(cluster)
+
+	go func() {
+		err := watcher.Run(ctx, time.Second, func(cluster *model.Cluster) error {
+			srv.Update(cluster)
+			return nil
+		})
+		if err != nil {
+			log.Error("Topology watch stopped", "err", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the cluster topology to a versioned JSON snapshot",
+	Long: `Fetch the cluster topology and write it as a versioned JSON snapshot.
+
+Snapshots can be rendered later, in any format and without cluster access,
+with 'k8sdd diagram --from-snapshot <file>'.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runSnapshot,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.Flags().StringVar(&rootOptions.kubeconfig, "kubeconfig", "", "path to kubeconfig (default: ~/.kube/config)")
+	snapshotCmd.Flags().StringVarP(&rootOptions.namespace, "namespace", "n", "", "namespace to capture (default: all non-system)")
+	snapshotCmd.Flags().BoolVarP(&rootOptions.allNamespaces, "all-namespaces", "A", false, "include all namespaces (including system)")
+	snapshotCmd.Flags().StringVarP(&rootOptions.output, "output", "o", "", "output file (default: stdout)")
+	snapshotCmd.Flags().BoolVar(&rootOptions.includeStorage, "include-storage", false, "include PVC/StorageClass layer")
+	snapshotCmd.Flags().BoolVar(&rootOptions.includeNetPols, "include-network-policies", false, "include NetworkPolicy layer")
+	snapshotCmd.Flags().BoolVar(&rootOptions.includePDBs, "include-pdbs", false, "include PodDisruptionBudgets and drain-safety warnings")
+	snapshotCmd.Flags().BoolVar(&rootOptions.includeConfig, "include-config-refs", false, "include workload references to ConfigMaps and Secrets")
+	snapshotCmd.Flags().BoolVar(&rootOptions.includeEPs, "include-endpoints", false, "wire services from EndpointSlices, showing endpoint readiness")
+	snapshotCmd.Flags().StringVar(&rootOptions.detail, "detail", detailWorkloads, "level of detail: workloads, or pods to show ReplicaSets and Pods")
+	snapshotCmd.Flags().StringVar(&rootOptions.view, "view", string(render.ViewNamespaces), "grouping: namespaces, nodes or zones to place workloads where their pods run")
+	snapshotCmd.Flags().BoolVarP(&rootOptions.quiet, "quiet", "q", false, "suppress progress indicators and log messages")
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) error {
+	configureLogger()
+
+	cluster, err := loadTopology(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	w, closeWriter, err := getOutputWriter()
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+
+	if err := renderWithSpinner(cluster, w, render.FormatJSON); err != nil {
+		return err
+	}
+
+	log.Info("Snapshot written successfully")
+	return nil
+}
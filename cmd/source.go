@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// Source kinds accepted by commands that compare topologies.
+const (
+	sourceContext   = "context"
+	sourceSnapshot  = "snapshot"
+	sourceFile      = "file"
+	sourceHelm      = "helm"
+	sourceKustomize = "kustomize"
+)
+
+const sourceHelp = `Each topology is given as <kind>:<value>:
+  context:<name>     live cluster from a kubeconfig context (empty name for the current one)
+  snapshot:<file>    JSON snapshot written by 'k8sdd snapshot'
+  file:<path>        manifest file or directory (- for stdin)
+  helm:<chart>       Helm chart templated with --values and --release-name
+  kustomize:<dir>    Kustomize overlay
+
+A bare "cluster" means the current context, a bare path ending in .json a
+snapshot, and any other bare path a manifest file or directory.`
+
+// topologySource identifies where a topology is loaded from.
+type topologySource struct {
+	kind  string
+	value string
+}
+
+func (s topologySource) String() string {
+	return s.kind + ":" + s.value
+}
+
+// parseSource parses a <kind>:<value> topology source.
+func parseSource(spec string) (topologySource, error) {
+	if spec == "cluster" {
+		return topologySource{kind: sourceContext}, nil
+	}
+
+	if kind, value, ok := strings.Cut(spec, ":"); ok {
+		switch kind {
+		case sourceContext:
+			return topologySource{kind: kind, value: value}, nil
+		case sourceSnapshot, sourceFile, sourceHelm, sourceKustomize:
+			if value == "" {
+				return topologySource{}, fmt.Errorf("source %q needs a value", spec)
+			}
+			return topologySource{kind: kind, value: value}, nil
+		}
+	}
+
+	if strings.HasSuffix(spec, ".json") {
+		return topologySource{kind: sourceSnapshot, value: spec}, nil
+	}
+	if _, err := os.Stat(spec); err == nil || spec == manifest.StdinPath {
+		return topologySource{kind: sourceFile, value: spec}, nil
+	}
+
+	return topologySource{}, fmt.Errorf("unknown source %q (expected context:, snapshot:, file:, helm: or kustomize:)", spec)
+}
+
+// load builds the topology of a source, applying the same namespace,
+// layer and detail flags as a diagram of it.
+func (s topologySource) load(ctx context.Context) (*model.Cluster, error) {
+	switch s.kind {
+	case sourceContext:
+		return fetchLiveTopology(ctx, s.value)
+	case sourceSnapshot:
+		return loadSnapshot(s.value)
+	default:
+		return s.loadManifests(ctx)
+	}
+}
+
+func (s topologySource) loadManifests(ctx context.Context) (*model.Cluster, error) {
+	loader := newManifestLoader()
+
+	var err error
+	switch s.kind {
+	case sourceFile:
+		err = loader.LoadPaths([]string{s.value}, os.Stdin)
+	case sourceHelm:
+		err = loader.LoadHelmChart(ctx, manifest.HelmChart{
+			Chart:       s.value,
+			ValuesFiles: rootOptions.helmValues,
+			ReleaseName: rootOptions.releaseName,
+		})
+	case sourceKustomize:
+		err = loader.LoadKustomization(s.value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loader.Err(); err != nil {
+		return nil, err
+	}
+	return loader.Cluster(), nil
+}
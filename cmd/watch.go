@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+// runWatch keeps the output file in sync with the live cluster until
+// interrupted.
+func runWatch(cmd *cobra.Command) error {
+	configureLogger()
+
+	if rootOptions.output == "" {
+		return fmt.Errorf("--watch requires --output")
+	}
+	if rootOptions.fromSnapshot != "" || hasManifestInput() {
+		return fmt.Errorf("--watch only works with a live cluster")
+	}
+
+	format, err := outputFormat()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := createClientWithSpinner("")
+	if err != nil {
+		return err
+	}
+
+	watcher, err := client.NewWatcher(fetchOptions())
+	if err != nil {
+		return err
+	}
+
+	if err := startWatcherWithSpinner(ctx, watcher); err != nil {
+		return err
+	}
+
+	log.Info("Watching cluster for changes", "output", rootOptions.output, "debounce", rootOptions.debounce)
+
+	var last []byte
+	return watcher.Run(ctx, rootOptions.debounce, func(cluster *model.Cluster) error {
+		var buf bytes.Buffer
+		renderer, err := render.New(format, &buf, renderOptions())
+		if err != nil {
+			return err
+		}
+		if err := renderer.Render(cluster); err != nil {
+			return err
+		}
+
+		// Status-only updates often leave the diagram unchanged
+		if bytes.Equal(buf.Bytes(), last) {
+			return nil
+		}
+		if err := writeFileAtomic(rootOptions.output, buf.Bytes()); err != nil {
+			return err
+		}
+		last = buf.Bytes()
+
+		log.Info("Diagram updated", "output", rootOptions.output)
+		return nil
+	})
+}
+
+func startWatcherWithSpinner(ctx context.Context, watcher *kube.Watcher) error {
+	if rootOptions.quiet {
+		return watcher.Start(ctx)
+	}
+
+	var startErr error
+	spinnerErr := spinner.New().
+		Title("Syncing informer caches...").
+		Action(func() {
+			startErr = watcher.Start(ctx)
+		}).
+		Run()
+
+	if spinnerErr != nil {
+		return spinnerErr
+	}
+	return startErr
+}
+
+// writeFileAtomic replaces path with data through a rename, so tools
+// watching the file never read a partially written diagram. The file keeps
+// the mode of the one it replaces, so a web server can still serve it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if info, err := os.Stat(path); err == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// createTemp creates a hidden file next to path. Unlike os.CreateTemp, which
+// uses 0600, it asks for 0644 like os.WriteFile, so a new diagram gets the
+// same mode, less the umask, as any other file written.
+func createTemp(path string) (*os.File, error) {
+	var err error
+	for range 100 {
+		name := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d", filepath.Base(path), rand.Uint32()))
+		var f *os.File
+		f, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,160 @@
+package validation_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vieitesss/k8s-d2/pkg/diff"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+func TestCompare_Fixtures(t *testing.T) {
+	oldCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	newCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	if diff.Compare(oldCluster, newCluster).HasChanges() {
+		t.Fatal("expected identical topologies to have no changes")
+	}
+
+	ns := &newCluster.Namespaces[0]
+	ns.Deployments[0].Replicas++
+	removed := ns.Services[0]
+	ns.Services = ns.Services[1:]
+	ns.Deployments = append(ns.Deployments, model.Workload{Name: "worker", Kind: model.KindDeployment, Replicas: 1})
+
+	result := diff.Compare(oldCluster, newCluster)
+	if !result.HasChanges() {
+		t.Fatal("expected changes")
+	}
+
+	statuses := make(map[model.Ref]diff.Status)
+	for _, rd := range result.Namespaces[0].Resources {
+		statuses[rd.Ref] = rd.Status
+	}
+
+	expected := map[model.Ref]diff.Status{
+		{Kind: model.KindDeployment, Namespace: ns.Name, Name: ns.Deployments[0].Name}: diff.StatusChanged,
+		{Kind: model.KindDeployment, Namespace: ns.Name, Name: "worker"}:               diff.StatusAdded,
+		{Kind: model.KindService, Namespace: ns.Name, Name: removed.Name}:              diff.StatusRemoved,
+	}
+	for ref, status := range expected {
+		if statuses[ref] != status {
+			t.Errorf("%s %s: expected %s, got %q", ref.Kind, ref.Name, status, statuses[ref])
+		}
+	}
+
+	for _, e := range result.Edges {
+		if e.Edge.From.Name == removed.Name && e.Status != diff.StatusRemoved {
+			t.Errorf("expected edges of removed service %s to be removed, got %s", removed.Name, e.Status)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := diff.NewD2Renderer(&buf, 3).Render(result); err != nil {
+		t.Fatalf("Failed to render diff: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{"(added)", "(removed)", "replicas: ", `style.fill: "#d4f7d4"`, `style.fill: "#f9d0d0"`, `style.fill: "#ffe4b3"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("diff diagram missing %q", want)
+		}
+	}
+}
+
+func TestDrift_Report(t *testing.T) {
+	expected, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	live, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	if report := diff.Drift(expected, live); report.HasDrift() {
+		t.Fatalf("expected no drift, got %s", report.Summary())
+	}
+
+	ns := &live.Namespaces[0]
+	ns.Services[0].Type = "LoadBalancer"
+	missing := ns.Deployments[0].Name
+	ns.Deployments = ns.Deployments[1:]
+	ns.DaemonSets = append(ns.DaemonSets, model.Workload{Name: "node-agent", Kind: model.KindDaemonSet})
+	// Namespaces outside the expected topology are not compared
+	live.Namespaces = append(live.Namespaces, model.Namespace{Name: "unmanaged", Deployments: []model.Workload{{Name: "x", Kind: model.KindDeployment}}})
+
+	report := diff.Drift(expected, live)
+	if len(report.Missing) != 1 || report.Missing[0].Name != missing {
+		t.Errorf("expected %s to be missing, got %v", missing, report.Missing)
+	}
+	if len(report.Extra) != 1 || report.Extra[0].Name != "node-agent" {
+		t.Errorf("expected node-agent to be extra, got %v", report.Extra)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0].Fields[0] != (diff.FieldDrift{Field: "type", Expected: expected.Namespaces[0].Services[0].Type, Live: "LoadBalancer"}) {
+		t.Errorf("expected a service type mismatch, got %v", report.Mismatched)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("Failed to write text report: %v", err)
+	}
+	if !strings.Contains(text.String(), "type: expected ClusterIP, live LoadBalancer") {
+		t.Errorf("text report missing mismatch:\n%s", text.String())
+	}
+
+	var doc struct {
+		Missing    []model.Ref     `json:"missing"`
+		Extra      []model.Ref     `json:"extra"`
+		Mismatched []diff.Mismatch `json:"mismatched"`
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write JSON report: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if len(doc.Missing) != 1 || len(doc.Extra) != 1 || len(doc.Mismatched) != 1 {
+		t.Errorf("unexpected JSON report: %s", buf.String())
+	}
+}
+
+func TestDrift_CronJobRuns(t *testing.T) {
+	expected, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	live, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	backup := model.Workload{Name: "nightly-backup", Kind: model.KindCronJob, Batch: &model.Batch{Schedule: "0 2 * * *"}}
+	expected.Namespaces[0].CronJobs = []model.Workload{backup}
+	ns := &live.Namespaces[0]
+	ns.CronJobs = []model.Workload{backup}
+	ns.Jobs = []model.Workload{
+		{Name: "nightly-backup-29000000", Kind: model.KindJob, Batch: &model.Batch{CronJob: backup.Name}},
+		{Name: "migrate", Kind: model.KindJob, Batch: &model.Batch{}},
+	}
+
+	// Only the Job nobody scheduled is extra
+	report := diff.Drift(expected, live)
+	if len(report.Extra) != 1 || report.Extra[0].Name != "migrate" {
+		t.Errorf("expected only migrate to be extra, got %v", report.Extra)
+	}
+
+	ns.Jobs = ns.Jobs[:1]
+	if result := diff.Compare(expected, live); result.HasChanges() {
+		t.Error("expected a CronJob run to leave the snapshot diff unchanged")
+	}
+}
@@ -0,0 +1,419 @@
+package validation_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vieitesss/k8s-d2/pkg/diff"
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestFetchTopology_FakeClientset(t *testing.T) {
+	expected, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	client := kube.NewClientFromInterface(fake.NewSimpleClientset(fixtureObjects(t)...))
+	cluster, err := client.FetchTopology(context.Background(), kube.FetchOptions{Namespace: testNamespace, IncludeStorage: true, IncludeConfigRefs: true})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+
+	if report := diff.Drift(expected, cluster); report.HasDrift() {
+		t.Errorf("fetched topology differs from fixtures: %s", report.Summary())
+	}
+}
+
+func TestWatcher_RebuildsOnChange(t *testing.T) {
+	clientset := fake.NewSimpleClientset(fixtureObjects(t)...)
+	client := kube.NewClientFromInterface(clientset)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watcher, err := client.NewWatcher(kube.FetchOptions{Namespace: testNamespace})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := watcher.Start(ctx); err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+
+	before, err := watcher.Topology(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read cached topology: %v", err)
+	}
+	fetched, err := client.FetchTopology(ctx, kube.FetchOptions{Namespace: testNamespace})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	if diff.Compare(fetched, before).HasChanges() {
+		t.Fatal("cached topology differs from the API topology")
+	}
+
+	worker := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: testNamespace}}
+	if _, err := clientset.AppsV1().Deployments(testNamespace).Create(ctx, worker, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create deployment: %v", err)
+	}
+
+	select {
+	case <-watcher.Changes():
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for a change notification")
+	}
+
+	after, err := watcher.Topology(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read cached topology: %v", err)
+	}
+	result := diff.Compare(before, after)
+	if !result.HasChanges() {
+		t.Fatal("expected the new deployment in the cached topology")
+	}
+	for _, rd := range result.Namespaces[0].Resources {
+		if rd.Ref.Name == "worker" && rd.Status != diff.StatusAdded {
+			t.Errorf("expected worker to be added, got %s", rd.Status)
+		}
+	}
+}
+
+func TestFetchTopology_GatewayAPI(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "gateway.networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "gateways"}, {Name: "httproutes"}},
+	}}
+
+	gateway := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]any{"name": "public", "namespace": testNamespace},
+		"spec": map[string]any{
+			"gatewayClassName": "envoy",
+			"listeners":        []any{map[string]any{"name": "http", "protocol": "HTTP", "port": int64(80)}},
+		},
+	}}
+	route := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "web", "namespace": testNamespace},
+		"spec": map[string]any{
+			"parentRefs": []any{map[string]any{"name": "public"}},
+			"rules":      []any{map[string]any{"backendRefs": []any{map[string]any{"name": "web-service", "port": int64(80)}}}},
+		},
+	}}
+	gatewaysGVR := schema.GroupVersionResource{Group: kube.GatewayGroup, Version: "v1", Resource: "gateways"}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gatewaysGVR: "GatewayList",
+		{Group: kube.GatewayGroup, Version: "v1", Resource: "httproutes"}: "HTTPRouteList",
+	}, route)
+
+	// Seeded objects get a guessed resource ("gatewaies"), so create it instead
+	ctx := context.Background()
+	if _, err := dyn.Resource(gatewaysGVR).Namespace(testNamespace).Create(ctx, gateway, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	client := kube.NewClientFromInterfaces(clientset, dyn)
+	cluster, err := client.FetchTopology(ctx, kube.FetchOptions{Namespace: testNamespace})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+
+	ns := cluster.Namespaces[0]
+	if len(ns.Gateways) != 1 || ns.Gateways[0].Class != "envoy" || ns.Gateways[0].Listeners[0].Port != 80 {
+		t.Errorf("unexpected gateways: %+v", ns.Gateways)
+	}
+	if len(ns.Routes) != 1 || len(ns.Routes[0].Backends) != 1 || ns.Routes[0].Backends[0].Service != "web-service" {
+		t.Errorf("unexpected routes: %+v", ns.Routes)
+	}
+}
+
+// fixtureObjects decodes the fixtures into typed objects for a fake clientset.
+func fixtureObjects(t *testing.T) []runtime.Object {
+	t.Helper()
+
+	data, err := loadAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	decoder := scheme.Codecs.UniversalDeserializer()
+	var objects []runtime.Object
+	for _, file := range data {
+		for doc := range bytes.SplitSeq(file, []byte("\n---\n")) {
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+			obj, _, err := decoder.Decode(doc, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to decode fixture: %v", err)
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+func TestFetchTopology_PodDetail(t *testing.T) {
+	controller := func(kind, name string) []metav1.OwnerReference {
+		isController := true
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+	replicaSet := func(name string, replicas int32) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, OwnerReferences: controller("Deployment", "api-backend")},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+			Status:     appsv1.ReplicaSetStatus{ReadyReplicas: replicas},
+		}
+	}
+	pod := func(name, ownerKind, owner, claim string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, OwnerReferences: controller(ownerKind, owner)},
+			Spec:       corev1.PodSpec{NodeName: "worker-1", Containers: []corev1.Container{{Name: "app"}}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 2}},
+			},
+		}
+		if claim != "" {
+			p.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}
+			p.Spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			}}}
+		}
+		return p
+	}
+
+	objects := append(fixtureObjects(t),
+		replicaSet("api-backend-5d8f", 2),
+		replicaSet("api-backend-old", 0),
+		pod("api-backend-5d8f-abcde", "ReplicaSet", "api-backend-5d8f", ""),
+		pod("database-0", "StatefulSet", "database", "data-database-0"),
+		pod("database-1", "StatefulSet", "database", "data-database-1"),
+		pod("stray", "ReplicaSet", "unknown", ""),
+	)
+	client := kube.NewClientFromInterface(fake.NewSimpleClientset(objects...))
+	cluster, err := client.FetchTopology(context.Background(), kube.FetchOptions{Namespace: testNamespace, IncludeStorage: true, IncludePods: true})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+
+	ns := &cluster.Namespaces[0]
+	api := ns.Workload(model.KindDeployment, "api-backend")
+	if len(api.ReplicaSets) != 1 || len(api.ReplicaSets[0].Pods) != 1 {
+		t.Fatalf("expected 1 ReplicaSet with 1 pod, got %+v", api.ReplicaSets)
+	}
+	if p := api.ReplicaSets[0].Pods[0]; p.Phase != "Running" || p.Restarts != 2 || p.Node != "worker-1" {
+		t.Errorf("unexpected pod: %+v", p)
+	}
+	if db := ns.Workload(model.KindStatefulSet, "database"); len(db.Pods) != 2 {
+		t.Fatalf("expected 2 database pods, got %+v", db.Pods)
+	}
+
+	var buf bytes.Buffer
+	if err := render.NewD2Renderer(&buf, 0).Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		`api_backend -> rs_api_backend_5d8f`,
+		`rs_api_backend_5d8f -> pod_api_backend_5d8f_abcde`,
+		`database -> pod_database_0`,
+		`pod_database_1 -> pvc_data_database_1: "/data (rw)"`,
+		`▤ api-backend-5d8f (2/2 ready)`,
+		`▢ database-0\nRunning, 2 restarts\nnode: worker-1`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in D2 output", want)
+		}
+	}
+	for _, unwanted := range []string{"rs_api_backend_old", "pod_stray", "database -> pvc_"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("unexpected %q in D2 output", unwanted)
+		}
+	}
+}
+
+func TestFetchTopology_DriftScopedToExpectedNamespaces(t *testing.T) {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+`
+	// Manifests without metadata.namespace land in the default namespace
+	expected, err := manifest.Load([]string{manifest.StdinPath}, strings.NewReader(manifests), manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	if names := expected.NamespaceNames(); len(names) != 1 || names[0] != manifest.DefaultNamespace {
+		t.Fatalf("expected only namespace %s, got %v", manifest.DefaultNamespace, names)
+	}
+
+	// The API server puts the same objects in the default namespace
+	objects := []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: manifest.DefaultNamespace}}}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for doc := range strings.SplitSeq(manifests, "\n---\n") {
+		obj, _, err := decoder.Decode([]byte(doc), nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to decode manifest: %v", err)
+		}
+		obj.(metav1.Object).SetNamespace(manifest.DefaultNamespace)
+		objects = append(objects, obj)
+	}
+	client := kube.NewClientFromInterface(fake.NewSimpleClientset(objects...))
+
+	// Without scoping, default is skipped as a system namespace
+	live, err := client.FetchTopology(context.Background(), kube.FetchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	if report := diff.Drift(expected, live); len(report.Missing) != 2 {
+		t.Errorf("expected both resources missing from the unscoped fetch, got %s", report.Summary())
+	}
+
+	live, err = client.FetchTopology(context.Background(), kube.FetchOptions{Namespaces: expected.NamespaceNames()})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	if report := diff.Drift(expected, live); report.HasDrift() {
+		t.Errorf("expected no drift in the scoped fetch, got %s", report.Summary())
+	}
+}
+
+func TestFetchTopology_DriftIgnoresStatus(t *testing.T) {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		t.Fatalf("Failed to find project root: %v", err)
+	}
+	fixturesDir := filepath.Join(projectRoot, "test", "fixtures")
+	paths := []string{filepath.Join(fixturesDir, "base"), filepath.Join(fixturesDir, "storage")}
+	expected, err := manifest.Load(paths, nil, manifest.Options{IncludeStorage: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	// A claim that leaves out its class gets the cluster default
+	expected.Namespaces[0].PVCs[0].StorageClass = ""
+
+	// The controllers fill in the status the manifests leave out, and the
+	// provisioner rounds the claim up
+	objects := fixtureObjects(t)
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.DaemonSet:
+			o.Status.DesiredNumberScheduled = 3
+		case *corev1.PersistentVolumeClaim:
+			o.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}
+		}
+	}
+	client := kube.NewClientFromInterface(fake.NewSimpleClientset(objects...))
+
+	live, err := client.FetchTopology(context.Background(), kube.FetchOptions{Namespace: testNamespace, IncludeStorage: true})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	if replicas := live.Namespaces[0].DaemonSets[0].Replicas; replicas != 3 {
+		t.Fatalf("expected 3 desired DaemonSet pods, got %d", replicas)
+	}
+	if report := diff.Drift(expected, live); report.HasDrift() {
+		t.Errorf("expected no drift from status fields, got %s", report.Summary())
+	}
+
+	// A class the manifest names is still compared
+	expected.Namespaces[0].PVCs[0].StorageClass = "fast"
+	report := diff.Drift(expected, live)
+	if len(report.Mismatched) != 1 || report.Mismatched[0].Fields[0].Field != "storageClass" {
+		t.Errorf("expected a storage class mismatch, got %+v", report.Mismatched)
+	}
+}
+
+func TestFetchTopology_ForbiddenOptionalResources(t *testing.T) {
+	expected, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	// A read-only ServiceAccount without access to these types has no
+	// ingresses, jobs or autoscalers in its topology
+	expected.Namespaces[0].Ingresses = nil
+
+	clientset := fake.NewSimpleClientset(fixtureObjects(t)...)
+	for _, resource := range []string{"horizontalpodautoscalers", "ingresses", "jobs", "cronjobs"} {
+		clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			gr := schema.GroupResource{Group: action.GetResource().Group, Resource: action.GetResource().Resource}
+			return true, nil, apierrors.NewForbidden(gr, "", errors.New("read-only ServiceAccount"))
+		})
+	}
+	client := kube.NewClientFromInterface(clientset)
+
+	cluster, err := client.FetchTopology(context.Background(), kube.FetchOptions{Namespace: testNamespace, IncludeStorage: true, IncludeConfigRefs: true})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	if report := diff.Drift(expected, cluster); report.HasDrift() {
+		t.Errorf("fetched topology differs from fixtures: %s", report.Summary())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	watcher, err := client.NewWatcher(kube.FetchOptions{Namespace: testNamespace})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := watcher.Start(ctx); err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+	cached, err := watcher.Topology(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read cached topology: %v", err)
+	}
+	if len(cached.Namespaces[0].Ingresses) != 0 {
+		t.Errorf("expected no ingresses in the cached topology, got %v", cached.Namespaces[0].Ingresses)
+	}
+}
@@ -0,0 +1,591 @@
+package validation_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vieitesss/k8s-d2/internal/validation"
+	"github.com/vieitesss/k8s-d2/pkg/manifest"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+func TestManifestLoader_Fixtures(t *testing.T) {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		t.Fatalf("Failed to find project root: %v", err)
+	}
+
+	// Load fixture directories through the public loader
+	fixturesDir := filepath.Join(projectRoot, "test", "fixtures")
+	paths := []string{filepath.Join(fixturesDir, "base"), filepath.Join(fixturesDir, "storage")}
+	cluster, err := manifest.Load(paths, nil, manifest.Options{IncludeStorage: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	if len(cluster.Namespaces) != 1 || cluster.Namespaces[0].Name != testNamespace {
+		t.Fatalf("expected only namespace %s, got %+v", testNamespace, cluster.Namespaces)
+	}
+
+	// Render D2 output
+	var buf bytes.Buffer
+	renderer := render.NewD2Renderer(&buf, 0)
+	if err := renderer.Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+
+	// Validate against the topology expected from the fixtures
+	expectedCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	validator := validation.NewD2Validator(expectedCluster, buf.String())
+
+	if err := validator.ValidateResources(); err != nil {
+		t.Errorf("Resource validation failed: %v", err)
+	}
+
+	if err := validator.ValidateServiceConnections(); err != nil {
+		t.Errorf("Service connection validation failed: %v", err)
+	}
+
+	if err := validator.ValidatePVCConnections(); err != nil {
+		t.Errorf("PVC connection validation failed: %v", err)
+	}
+
+	if err := validator.ValidateConfigInfo(); err != nil {
+		t.Errorf("Config info validation failed: %v", err)
+	}
+}
+
+func TestManifestLoader_Namespaces(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: team-a
+spec:
+  selector:
+    matchLabels:
+      app: api
+---
+# comment-only documents are ignored
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  selector:
+    app: api
+`
+
+	cluster, err := manifest.Load([]string{manifest.StdinPath}, strings.NewReader(input), manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	if len(cluster.Namespaces) != 2 {
+		t.Fatalf("expected 2 namespaces, got %d", len(cluster.Namespaces))
+	}
+
+	// Namespaces are sorted; unnamespaced objects land in "default"
+	if ns := cluster.Namespaces[0]; ns.Name != manifest.DefaultNamespace || len(ns.Services) != 1 {
+		t.Errorf("expected service in default namespace, got %+v", ns)
+	}
+
+	if ns := cluster.Namespaces[1]; ns.Name != "team-a" || len(ns.Deployments) != 1 {
+		t.Errorf("expected deployment in team-a, got %+v", ns)
+	}
+
+	if replicas := cluster.Namespaces[1].Deployments[0].Replicas; replicas != 1 {
+		t.Errorf("expected default of 1 replica, got %d", replicas)
+	}
+}
+
+func TestManifestLoader_ReportsBadDocuments(t *testing.T) {
+	input := `kind: Deployment
+metadata:
+  name: ok
+---
+kind: Deployment
+spec:
+  replicas: many
+---
+metadata:
+  name: no-kind
+`
+
+	_, err := manifest.Load([]string{manifest.StdinPath}, strings.NewReader(input), manifest.Options{})
+	if err == nil {
+		t.Fatal("expected an error for bad documents")
+	}
+
+	var docErr *manifest.DocumentError
+	if !errors.As(err, &docErr) || docErr.Index != 2 {
+		t.Errorf("expected first error for document 2, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "document 3") {
+		t.Errorf("expected document 3 to be reported, got %v", err)
+	}
+}
+
+func TestManifestLoader_Kustomization(t *testing.T) {
+	// Build a kustomization that prefixes the base fixtures
+	fixtureData, err := loadBaseFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	dir := t.TempDir()
+	kustomization := "namePrefix: prod-\nresources:\n"
+	for i, file := range baseFixtures {
+		if err := os.WriteFile(filepath.Join(dir, file), fixtureData[i], 0o644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		kustomization += "- " + file + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization), 0o644); err != nil {
+		t.Fatalf("Failed to write kustomization: %v", err)
+	}
+
+	loader := manifest.NewLoader(manifest.Options{})
+	if err := loader.LoadKustomization(dir); err != nil {
+		t.Fatalf("Failed to build kustomization: %v", err)
+	}
+	if err := loader.Err(); err != nil {
+		t.Fatalf("Failed to load kustomization output: %v", err)
+	}
+
+	cluster := loader.Cluster()
+	if len(cluster.Namespaces) != 1 {
+		t.Fatalf("expected 1 namespace, got %d", len(cluster.Namespaces))
+	}
+
+	ns := cluster.Namespaces[0]
+	if len(ns.Deployments) != 2 || ns.Deployments[0].Name != "prod-api-backend" {
+		t.Errorf("expected prefixed deployments, got %+v", ns.Deployments)
+	}
+}
+
+func TestManifestLoader_GatewayAPI(t *testing.T) {
+	cluster, err := loadManifestFixtures("gateway-api", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	if len(cluster.Namespaces) != 3 || cluster.Namespaces[1].Name != "edge" {
+		t.Fatalf("expected billing, edge and shop namespaces, got %+v", cluster.Namespaces)
+	}
+	edge := cluster.Namespaces[1]
+	if len(edge.Gateways) != 1 || len(edge.Routes) != 1 {
+		t.Fatalf("expected 1 gateway and 1 route, got %+v", edge)
+	}
+
+	// The grant in shop allows the api backend; billing has no grant
+	denied := make(map[string]bool)
+	for _, b := range edge.Routes[0].Backends {
+		denied[b.Namespace+"/"+b.Service] = b.Denied
+	}
+	if denied["shop/api"] || !denied["billing/invoices"] || denied["edge/web"] {
+		t.Errorf("unexpected ReferenceGrant resolution: %v", denied)
+	}
+
+	route := ref(model.KindHTTPRoute, "edge", "storefront")
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: ref(model.KindGateway, "edge", "public"), To: route, Type: model.EdgeAttaches, Label: "https"},
+		model.Edge{From: route, To: ref(model.KindService, "edge", "web"), Type: model.EdgeRoutes, Label: "weight 90"},
+		model.Edge{From: route, To: ref(model.KindService, "edge", "web-canary"), Type: model.EdgeRoutes, Label: "weight 10"},
+		model.Edge{From: route, To: ref(model.KindService, "shop", "api"), Type: model.EdgeRoutes, Label: "weight 1"},
+		model.Edge{From: route, To: ref(model.KindService, "billing", "invoices"), Type: model.EdgeRoutes, Label: "weight 1", Broken: true},
+	)
+}
+
+func TestManifestLoader_NetworkPolicies(t *testing.T) {
+	cluster, err := loadManifestFixtures("network-policies", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	if policies := cluster.Namespaces[0].NetworkPolicies; len(policies) != 0 {
+		t.Errorf("expected no network policies without the option, got %d", len(policies))
+	}
+
+	cluster, err = loadManifestFixtures("network-policies", manifest.Options{IncludeNetworkPolicies: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+	if len(ns.NetworkPolicies) != 2 {
+		t.Fatalf("expected 2 network policies, got %+v", ns.NetworkPolicies)
+	}
+
+	access := make(map[string]string)
+	for _, w := range ns.AllWorkloads() {
+		access[w.Name] = w.NetworkAccess
+	}
+	want := map[string]string{"web": model.AccessOpen, "api": "", "db": model.AccessIsolated, "worker": model.AccessOpen}
+	for name, a := range want {
+		if access[name] != a {
+			t.Errorf("expected %s to be %q, got %q", name, a, access[name])
+		}
+	}
+
+	if blocks := ns.IPBlocks(); len(blocks) != 1 || blocks[0].CIDR != "10.0.0.0/8" || len(blocks[0].Except) != 1 {
+		t.Errorf("unexpected ipBlocks %+v", blocks)
+	}
+
+	deployment := func(name string) model.Ref { return ref(model.KindDeployment, "shop", name) }
+	edges := render.ClusterEdges(cluster)
+	expectEdges(t, edges,
+		model.Edge{From: deployment("web"), To: deployment("api"), Type: model.EdgeAllows, Label: "8080/TCP"},
+		model.Edge{From: deployment("worker"), To: deployment("api"), Type: model.EdgeAllows, Label: "8080/TCP"},
+		model.Edge{From: ref(model.KindIPBlock, "shop", "10.0.0.0/8"), To: deployment("api"), Type: model.EdgeAllows},
+	)
+	for _, e := range edges {
+		if e.From == deployment("db") || e.To == deployment("db") || (e.From == deployment("web") && e.To == deployment("worker")) {
+			t.Errorf("unexpected edge %+v", e)
+		}
+	}
+}
+
+func TestManifestLoader_Jobs(t *testing.T) {
+	cluster, err := loadManifestFixtures("jobs", manifest.Options{IncludeStorage: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+	if len(ns.CronJobs) != 1 || len(ns.Jobs) != 1 {
+		t.Fatalf("expected 1 CronJob and 1 Job, got %+v", ns)
+	}
+
+	cronJob := ns.CronJobs[0].Batch
+	if cronJob.Schedule != "0 2 * * *" || !cronJob.Suspend || cronJob.LastRun != model.RunSucceeded {
+		t.Errorf("unexpected CronJob state: %+v", cronJob)
+	}
+	job := ns.Jobs[0]
+	if job.Replicas != 2 || job.Batch.CronJob != "nightly-backup" || job.Batch.LastRun != model.RunRunning || job.Batch.Active != 1 {
+		t.Errorf("unexpected Job: %+v (batch %+v)", job, job.Batch)
+	}
+
+	cron := ref(model.KindCronJob, "data", "nightly-backup")
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: cron, To: ref(model.KindJob, "data", "nightly-backup-29000000"), Type: model.EdgeOwns},
+		model.Edge{From: cron, To: ref(model.KindPVC, "data", "backups"), Type: model.EdgeMounts, Label: "/backup (rw)"},
+	)
+}
+
+func TestManifestLoader_Autoscalers(t *testing.T) {
+	cluster, err := loadManifestFixtures("autoscalers", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+
+	api := ns.Workload(model.KindDeployment, "api")
+	if a := api.Autoscaler; a == nil || a.Kind != model.KindHPA || a.MinReplicas != 2 || a.MaxReplicas != 10 || !slices.Equal(a.Metrics, []string{"cpu 45% / 70%"}) {
+		t.Fatalf("unexpected api autoscaler: %+v", a)
+	}
+	if api.Replicas != 3 {
+		t.Errorf("expected 3 api replicas, got %d", api.Replicas)
+	}
+	worker := ns.Workload(model.KindDeployment, "worker").Autoscaler
+	if worker == nil || worker.Kind != model.KindScaledObject || worker.MinReplicas != 0 || worker.MaxReplicas != 20 || !slices.Equal(worker.Metrics, []string{"rabbitmq trigger"}) {
+		t.Fatalf("unexpected worker autoscaler: %+v", worker)
+	}
+
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: ref(model.KindHPA, "shop", "api"), To: ref(model.KindDeployment, "shop", "api"), Type: model.EdgeScales},
+		model.Edge{From: ref(model.KindScaledObject, "shop", "worker"), To: ref(model.KindDeployment, "shop", "worker"), Type: model.EdgeScales},
+	)
+}
+
+func TestManifestLoader_PDBs(t *testing.T) {
+	cluster, err := loadManifestFixtures("pdbs", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	if pdbs := cluster.Namespaces[0].PDBs; len(pdbs) != 0 {
+		t.Fatalf("expected no PDBs without IncludePDBs, got %d", len(pdbs))
+	}
+
+	cluster, err = loadManifestFixtures("pdbs", manifest.Options{IncludePDBs: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+	if len(ns.PDBs) != 2 {
+		t.Fatalf("expected 2 PDBs, got %d", len(ns.PDBs))
+	}
+
+	// minAvailable 3 of 3 replicas leaves no disruption
+	api, worker := &ns.PDBs[0], &ns.PDBs[1]
+	w := ns.Workload(model.KindDeployment, "api")
+	if !api.Selects(w) || !api.Blocks(w.Replicas) || api.Budget() != "minAvailable 3" {
+		t.Errorf("expected api budget to cover api and block drains, got %+v", api)
+	}
+	// The worker budget's selector has a typo
+	for _, w := range ns.AllWorkloads() {
+		if worker.Selects(&w) {
+			t.Errorf("expected worker budget to match no workload, matches %s", w.Name)
+		}
+	}
+}
+
+func TestManifestLoader_ConfigRefs(t *testing.T) {
+	cluster, err := loadManifestFixtures("config-refs", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	if refs := cluster.Namespaces[0].Workload(model.KindDeployment, "api").ConfigRefs; len(refs) != 0 {
+		t.Fatalf("expected no config refs without IncludeConfigRefs, got %+v", refs)
+	}
+
+	cluster, err = loadManifestFixtures("config-refs", manifest.Options{IncludeConfigRefs: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	refs := cluster.Namespaces[0].Workload(model.KindDeployment, "api").ConfigRefs
+	if len(refs) != 5 {
+		t.Fatalf("expected 5 config refs, got %+v", refs)
+	}
+
+	// Optional references to absent objects are not missing
+	missing := make(map[string]bool)
+	for _, r := range refs {
+		missing[r.Kind+"/"+r.Name] = r.Missing
+	}
+	wantMissing := map[string]bool{"ConfigMap/api-config": false, "ConfigMap/flags": false, "Secret/db-credentials": false, "Secret/api-tls": true, "Secret/registry": true}
+	for name, want := range wantMissing {
+		if missing[name] != want {
+			t.Errorf("expected %s missing=%v, got %v", name, want, missing[name])
+		}
+	}
+
+	api := ref(model.KindDeployment, "shop", "api")
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: api, To: ref(model.KindConfigMap, "shop", "api-config"), Type: model.EdgeUses, Label: "volume, envFrom"},
+		model.Edge{From: api, To: ref(model.KindSecret, "shop", "db-credentials"), Type: model.EdgeUses, Label: "env"},
+		model.Edge{From: api, To: ref(model.KindSecret, "shop", "registry"), Type: model.EdgeUses, Label: "imagePullSecret"},
+	)
+}
+
+func TestManifestLoader_SelectorSemantics(t *testing.T) {
+	cluster, err := loadManifestFixtures("selectors", manifest.Options{IncludePDBs: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+	api := ns.Workload(model.KindDeployment, "api")
+	if api.Selector == nil || len(api.Selector.MatchExpressions) != 1 {
+		t.Fatalf("expected the full selector to be kept, got %+v", api.Selector)
+	}
+
+	// The Exists expression covers api through its template labels
+	if pdb := &ns.PDBs[0]; !pdb.Selects(api) {
+		t.Errorf("expected budget %s to cover api", pdb.Name)
+	}
+
+	edges := render.ClusterEdges(cluster)
+	expectEdges(t, edges,
+		model.Edge{From: ref(model.KindService, "shop", "backend"), To: ref(model.KindDeployment, "shop", "api"), Type: model.EdgeSelects},
+	)
+	if e, ok := findEdge(edges, ref(model.KindService, "shop", "frontend"), ref(model.KindDeployment, "shop", "api")); ok {
+		t.Errorf("unexpected edge %+v", e)
+	}
+}
+
+func TestManifestLoader_Endpoints(t *testing.T) {
+	cluster, err := loadManifestFixtures("endpoints", manifest.Options{IncludeEndpoints: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ns := &cluster.Namespaces[0]
+	if w := ns.Workload(model.KindDeployment, "api"); len(w.ReplicaSets) != 0 {
+		t.Errorf("expected pods to stay detached without IncludePods, got %+v", w.ReplicaSets)
+	}
+
+	ready := make(map[string]string)
+	for _, svc := range ns.Services {
+		if svc.Endpoints != nil {
+			ready[svc.Name] = model.FormatReady(svc.Endpoints.Ready, svc.Endpoints.Total)
+		}
+	}
+	if ready["api"] != "1/2 ready" || ready["legacy-db"] != "0/1 ready" {
+		t.Errorf("unexpected endpoint readiness %v", ready)
+	}
+
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: ref(model.KindService, "shop", "api"), To: ref(model.KindDeployment, "shop", "api"), Type: model.EdgeServes, Label: "1/2 ready"},
+		model.Edge{From: ref(model.KindService, "shop", "legacy-db"), To: ref(model.KindAddress, "shop", "10.0.0.5"), Type: model.EdgeServes, Label: "0/1 ready", Broken: true},
+	)
+}
+
+func TestManifestLoader_ServicePorts(t *testing.T) {
+	cluster, err := loadManifestFixtures("service-ports", manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+	ports := cluster.Namespaces[0].Services[0].Ports
+	if len(ports) != 2 || ports[0].TargetPortName != "http" || ports[0].NodePort != 30080 || ports[1].TargetPort != 53 {
+		t.Fatalf("unexpected ports %+v", ports)
+	}
+
+	svc := ref(model.KindService, "shop", "web")
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: svc, To: ref(model.KindDeployment, "shop", "web"), Type: model.EdgeSelects, Label: "80→8080/TCP (node 30080)\n53→53/UDP"},
+		// No container declares the name, so it is kept
+		model.Edge{From: svc, To: ref(model.KindDeployment, "shop", "web-legacy"), Type: model.EdgeSelects, Label: "80→http/TCP (node 30080)\n53→53/UDP"},
+	)
+}
+
+func TestManifestLoader_ServiceTypes(t *testing.T) {
+	internet := ref(model.KindInternet, "shop", "internet")
+	db := ref(model.KindService, "shop", "db")
+	common := []model.Edge{
+		{From: ref(model.KindService, "shop", "payments"), To: ref(model.KindDNSName, "shop", "api.payments.example.com"), Type: model.EdgeAliases},
+		{From: internet, To: ref(model.KindService, "shop", "edge"), Type: model.EdgeExposes},
+		{From: internet, To: ref(model.KindService, "shop", "edge-new"), Type: model.EdgeExposes},
+	}
+
+	tests := []struct {
+		name     string
+		opts     manifest.Options
+		expected []model.Edge
+	}{
+		{
+			name: "workloads",
+			expected: []model.Edge{
+				{From: db, To: ref(model.KindStatefulSet, "shop", "db"), Type: model.EdgeSelects, Label: "db-{0..1}\n5432→5432/TCP"},
+			},
+		},
+		{
+			name: "pods",
+			opts: manifest.Options{IncludePods: true},
+			expected: []model.Edge{
+				{From: db, To: ref(model.KindPod, "shop", "db-0"), Type: model.EdgeSelects, Label: "5432→5432/TCP"},
+				{From: db, To: ref(model.KindPod, "shop", "db-1"), Type: model.EdgeSelects, Label: "5432→5432/TCP"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := loadManifestFixtures("service-types", tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to load manifests: %v", err)
+			}
+
+			services := make(map[string]model.Service)
+			for _, svc := range cluster.Namespaces[0].Services {
+				services[svc.Name] = svc
+			}
+			if !services["db"].Headless || services["payments"].ExternalName != "api.payments.example.com" {
+				t.Errorf("unexpected db or payments service: %+v, %+v", services["db"], services["payments"])
+			}
+			if lb := services["edge"].LoadBalancer; !slices.Equal(lb, []string{"203.0.113.10", "edge.elb.example.com"}) {
+				t.Errorf("unexpected edge addresses %v", lb)
+			}
+			if lb := services["edge-new"].LoadBalancer; len(lb) != 0 {
+				t.Errorf("expected edge-new to be pending, got %v", lb)
+			}
+
+			expectEdges(t, render.ClusterEdges(cluster), slices.Concat(common, tt.expected)...)
+		})
+	}
+}
+
+func TestManifestLoader_CrossNamespaceEdges(t *testing.T) {
+	cluster, err := loadManifestFixtures("cross-namespace", manifest.Options{IncludeNetworkPolicies: true})
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	api := ref(model.KindService, "payments", "api")
+	web := ref(model.KindDeployment, "shop", "web")
+	route := ref(model.KindHTTPRoute, "shop", "checkout")
+	dns := ref(model.KindDNSName, "shop", "api.payments.svc.cluster.local")
+	cross := []model.Edge{
+		{From: ref(model.KindGateway, "infra", "public"), To: route, Type: model.EdgeAttaches},
+		// No ReferenceGrant in payments allows the route
+		{From: route, To: api, Type: model.EdgeRoutes, Broken: true},
+		{From: dns, To: api, Type: model.EdgeResolves},
+		{From: web, To: api, Type: model.EdgeCalls, Label: "API_URL, API_HOSTS"},
+		{From: web, To: ref(model.KindDeployment, "payments", "api"), Type: model.EdgeAllows, Label: "8080/TCP"},
+	}
+
+	edges := render.CrossNamespaceEdges(cluster)
+	if len(edges) != len(cross) {
+		t.Errorf("expected %d cross-namespace edges, got %d: %+v", len(cross), len(edges), edges)
+	}
+	expectEdges(t, edges, cross...)
+	// archive is not in the diagram, so LEGACY_URL draws no edge
+	for _, e := range edges {
+		if e.To.Namespace == "archive" {
+			t.Errorf("unexpected edge to a namespace outside the diagram: %+v", e)
+		}
+	}
+
+	expectEdges(t, render.ClusterEdges(cluster),
+		model.Edge{From: ref(model.KindService, "shop", "payments"), To: dns, Type: model.EdgeAliases},
+		model.Edge{From: web, To: ref(model.KindService, "shop", "cache"), Type: model.EdgeCalls, Label: "API_HOSTS"},
+	)
+
+	// Cross-namespace edges are drawn at the root with qualified paths
+	var buf bytes.Buffer
+	if err := render.NewD2Renderer(&buf, 3).Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+	expected, err := loadFixtures("cross-namespace", []string{"expected.d2"})
+	if err != nil {
+		t.Fatalf("Failed to load expected D2: %v", err)
+	}
+	if buf.String() != string(expected[0]) {
+		t.Errorf("D2 output differs from test/fixtures/cross-namespace/expected.d2:\n%s", buf.String())
+	}
+}
+
+func loadManifestFixtures(dir string, opts manifest.Options) (*model.Cluster, error) {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Load([]string{filepath.Join(projectRoot, "test", "fixtures", dir)}, nil, opts)
+}
+
+// ref builds a reference to a resource
+func ref(kind, namespace, name string) model.Ref {
+	return model.Ref{Kind: kind, Namespace: namespace, Name: name}
+}
+
+// findEdge returns the edge between two resources, if any
+func findEdge(edges []model.Edge, from, to model.Ref) (model.Edge, bool) {
+	i := slices.IndexFunc(edges, func(e model.Edge) bool { return e.From == from && e.To == to })
+	if i < 0 {
+		return model.Edge{}, false
+	}
+	return edges[i], true
+}
+
+// expectEdges checks that every expected edge was derived, with the same
+// type, label and broken flag
+func expectEdges(t *testing.T, edges []model.Edge, expected ...model.Edge) {
+	t.Helper()
+
+	for _, want := range expected {
+		got, ok := findEdge(edges, want.From, want.To)
+		if !ok {
+			t.Errorf("missing edge %s/%s -> %s/%s", want.From.Kind, want.From.Name, want.To.Kind, want.To.Name)
+			continue
+		}
+		if got != want {
+			t.Errorf("unexpected edge %s/%s -> %s/%s: got %+v, want %+v", want.From.Kind, want.From.Name, want.To.Kind, want.To.Name, got, want)
+		}
+	}
+}
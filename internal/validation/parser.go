@@ -7,6 +7,7 @@ import (
 	"github.com/vieitesss/k8s-d2/pkg/model"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"sigs.k8s.io/yaml"
 )
 
@@ -85,6 +86,8 @@ func (p *FixtureParser) parseYAMLDocument(doc []byte, ns *model.Namespace) error
 		return p.parseConfigMap(doc, ns)
 	case "Secret":
 		return p.parseSecret(doc, ns)
+	case "Ingress":
+		return p.parseIngress(doc, ns)
 	case "Namespace", "StorageClass":
 		// These don't need to be parsed into the model for validation
 		return nil
@@ -101,25 +104,7 @@ func (p *FixtureParser) parseDeployment(doc []byte, ns *model.Namespace) error {
 		return err
 	}
 
-	replicas := int32(1) // Default replica count
-	if dep.Spec.Replicas != nil {
-		replicas = *dep.Spec.Replicas
-	}
-
-	volumeMounts := kube.ExtractVolumeMounts(
-		dep.Spec.Template.Spec.Containers,
-		dep.Spec.Template.Spec.Volumes,
-	)
-
-	workload := model.Workload{
-		Name:         dep.Name,
-		Kind:         "Deployment",
-		Replicas:     replicas,
-		Labels:       dep.Spec.Selector.MatchLabels,
-		VolumeMounts: volumeMounts,
-	}
-
-	ns.Deployments = append(ns.Deployments, workload)
+	ns.Deployments = append(ns.Deployments, kube.ConvertDeployment(&dep))
 	return nil
 }
 
@@ -130,29 +115,7 @@ func (p *FixtureParser) parseStatefulSet(doc []byte, ns *model.Namespace) error
 		return err
 	}
 
-	replicas := int32(1) // Default replica count
-	if ss.Spec.Replicas != nil {
-		replicas = *ss.Spec.Replicas
-	}
-
-	// Extract volume mounts including generated names from volumeClaimTemplates
-	volumeMounts := kube.ExtractAllStatefulSetVolumeMounts(
-		ss.Spec.Template.Spec.Containers,
-		ss.Spec.Template.Spec.Volumes,
-		ss.Spec.VolumeClaimTemplates,
-		ss.Name,
-		replicas,
-	)
-
-	workload := model.Workload{
-		Name:         ss.Name,
-		Kind:         "StatefulSet",
-		Replicas:     replicas,
-		Labels:       ss.Spec.Selector.MatchLabels,
-		VolumeMounts: volumeMounts,
-	}
-
-	ns.StatefulSets = append(ns.StatefulSets, workload)
+	ns.StatefulSets = append(ns.StatefulSets, kube.ConvertStatefulSet(&ss))
 	return nil
 }
 
@@ -163,20 +126,7 @@ func (p *FixtureParser) parseDaemonSet(doc []byte, ns *model.Namespace) error {
 		return err
 	}
 
-	volumeMounts := kube.ExtractVolumeMounts(
-		ds.Spec.Template.Spec.Containers,
-		ds.Spec.Template.Spec.Volumes,
-	)
-
-	workload := model.Workload{
-		Name:         ds.Name,
-		Kind:         "DaemonSet",
-		Replicas:     0, // DaemonSets don't have a fixed replica count
-		Labels:       ds.Spec.Selector.MatchLabels,
-		VolumeMounts: volumeMounts,
-	}
-
-	ns.DaemonSets = append(ns.DaemonSets, workload)
+	ns.DaemonSets = append(ns.DaemonSets, kube.ConvertDaemonSet(&ds))
 	return nil
 }
 
@@ -187,21 +137,7 @@ func (p *FixtureParser) parseService(doc []byte, ns *model.Namespace) error {
 		return err
 	}
 
-	service := model.Service{
-		Name:     svc.Name,
-		Type:     string(svc.Spec.Type),
-		Selector: svc.Spec.Selector,
-	}
-
-	// Add ports if needed in the future
-	for _, port := range svc.Spec.Ports {
-		service.Ports = append(service.Ports, model.Port{
-			Port:       port.Port,
-			TargetPort: port.TargetPort.IntVal,
-		})
-	}
-
-	ns.Services = append(ns.Services, service)
+	ns.Services = append(ns.Services, kube.ConvertService(&svc))
 	return nil
 }
 
@@ -212,23 +148,18 @@ func (p *FixtureParser) parsePVC(doc []byte, ns *model.Namespace) error {
 		return err
 	}
 
-	storageClass := ""
-	if pvc.Spec.StorageClassName != nil {
-		storageClass = *pvc.Spec.StorageClassName
-	}
-
-	capacity := ""
-	if storage, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
-		capacity = storage.String()
-	}
+	ns.PVCs = append(ns.PVCs, kube.ConvertPVC(&pvc))
+	return nil
+}
 
-	pvcModel := model.PVC{
-		Name:         pvc.Name,
-		StorageClass: storageClass,
-		Capacity:     capacity,
+// parseIngress converts a Kubernetes Ingress to a model.Ingress
+func (p *FixtureParser) parseIngress(doc []byte, ns *model.Namespace) error {
+	var ing networkingv1.Ingress
+	if err := yaml.Unmarshal(doc, &ing); err != nil {
+		return err
 	}
 
-	ns.PVCs = append(ns.PVCs, pvcModel)
+	ns.Ingresses = append(ns.Ingresses, kube.ConvertIngress(&ing))
 	return nil
 }
 
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/vieitesss/k8s-d2/pkg/model"
 	"github.com/vieitesss/k8s-d2/pkg/render"
@@ -11,8 +12,8 @@ import (
 type Connection struct {
 	From  string // Source resource ID (e.g., "svc_web_service")
 	To    string // Target resource ID (e.g., "web_frontend")
-	Type  string // Connection type: "service-to-workload" or "workload-to-pvc"
-	Label string // Connection label for mount metadata (e.g., "/var/log (rw)")
+	Type  string // Connection type: "ingress-to-service", "service-to-workload" or "workload-to-pvc"
+	Label string // Connection label for ports, routes or mount metadata (e.g., "/var/log (rw)")
 }
 
 // RelationshipDeriver handles deriving connections between resources.
@@ -37,12 +38,18 @@ func (rd *RelationshipDeriver) ServiceToWorkloadConnections(ns *model.Namespace)
 	for _, svc := range ns.Services {
 		svcID := render.SanitizeID(svc.Name)
 		for _, w := range allWorkloads {
-			if render.LabelsMatch(svc.Selector, w.Labels) {
+			if render.LabelsMatch(svc.Selector, w.TemplateLabels()) {
 				wID := render.SanitizeID(w.Name)
+				label := model.FormatPorts(svc.Ports, &w)
+				// Headless services name the StatefulSet ordinals they reach
+				if svc.Headless && w.Kind == model.KindStatefulSet {
+					label = model.FormatOrdinals(&w) + "\n" + label
+				}
 				connections = append(connections, Connection{
-					From: fmt.Sprintf("svc_%s", svcID),
-					To:   wID,
-					Type: "service-to-workload",
+					From:  fmt.Sprintf("svc_%s", svcID),
+					To:    wID,
+					Type:  "service-to-workload",
+					Label: render.EscapeD2(label),
 				})
 			}
 		}
@@ -83,3 +90,33 @@ func (rd *RelationshipDeriver) WorkloadToPVCConnections(ns *model.Namespace) []C
 
 	return connections
 }
+
+// IngressToServiceConnections derives all ingress→service connections in a
+// namespace, one per backend service with every route to it in the label
+func (rd *RelationshipDeriver) IngressToServiceConnections(ns *model.Namespace) []Connection {
+	var connections []Connection
+
+	for _, ing := range ns.Ingresses {
+		ingID := render.SanitizeID(ing.Name)
+
+		// Group routes by backend service
+		routesByService := make(map[string][]string)
+		for _, route := range ing.Routes {
+			routesByService[route.Backend.Service] = append(routesByService[route.Backend.Service], model.FormatRoute(route))
+		}
+		if ing.DefaultBackend != nil {
+			routesByService[ing.DefaultBackend.Service] = append(routesByService[ing.DefaultBackend.Service], "default")
+		}
+
+		for svcName, routes := range routesByService {
+			connections = append(connections, Connection{
+				From:  fmt.Sprintf("ing_%s", ingID),
+				To:    fmt.Sprintf("svc_%s", render.SanitizeID(svcName)),
+				Type:  "ingress-to-service",
+				Label: strings.Join(routes, "\\n"),
+			})
+		}
+	}
+
+	return connections
+}
@@ -0,0 +1,436 @@
+package validation_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vieitesss/k8s-d2/internal/validation"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+func TestMermaidRenderer_Fixtures(t *testing.T) {
+	expectedCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	var buf bytes.Buffer
+	renderer, err := render.New(render.FormatMermaid, &buf, render.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	if err := renderer.Render(expectedCluster); err != nil {
+		t.Fatalf("Failed to render Mermaid: %v", err)
+	}
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "%% Generated by k8s-d2\nflowchart LR\n") {
+		t.Errorf("missing flowchart header")
+	}
+
+	if strings.Count(output, "subgraph ") != strings.Count(output, "  end\n") {
+		t.Errorf("unbalanced subgraphs")
+	}
+
+	// Every relationship the D2 output is validated against must be drawn
+	deriver := validation.NewRelationshipDeriver()
+	for _, ns := range expectedCluster.Namespaces {
+		prefix := render.SanitizeID(ns.Name) + "__"
+
+		for _, conn := range deriver.ServiceToWorkloadConnections(&ns) {
+			edge := prefix + conn.From + " --> " + prefix + conn.To
+			if conn.Label != "" {
+				label := strings.ReplaceAll(conn.Label, `\n`, "<br/>")
+				edge = prefix + conn.From + ` -->|"` + label + `"| ` + prefix + conn.To
+			}
+			if !strings.Contains(output, edge) {
+				t.Errorf("missing service edge: %s", edge)
+			}
+		}
+
+		for _, conn := range deriver.WorkloadToPVCConnections(&ns) {
+			label := strings.ReplaceAll(conn.Label, `\n`, "<br/>")
+			edge := prefix + conn.From + ` -->|"` + label + `"| ` + prefix + conn.To
+			if !strings.Contains(output, edge) {
+				t.Errorf("missing PVC edge: %s", edge)
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := render.ParseFormat("svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+
+	format, err := render.ParseFormat("Mermaid")
+	if err != nil || format != render.FormatMermaid {
+		t.Errorf("expected mermaid format, got %q (%v)", format, err)
+	}
+}
+
+func TestGraphRenderers_EdgeSet(t *testing.T) {
+	expectedCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	tests := []struct {
+		format render.Format
+		header string
+		edge   func(from, to string) string
+	}{
+		{render.FormatDOT, "digraph", func(from, to string) string { return `"` + from + `" -> "` + to + `"` }},
+		{render.FormatPlantUML, "@startuml", func(from, to string) string { return from + " --> " + to }},
+	}
+
+	deriver := validation.NewRelationshipDeriver()
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			renderer, err := render.New(tt.format, &buf, render.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create renderer: %v", err)
+			}
+			if err := renderer.Render(expectedCluster); err != nil {
+				t.Fatalf("Failed to render: %v", err)
+			}
+			output := buf.String()
+
+			if !strings.Contains(output, tt.header) {
+				t.Errorf("missing %s header", tt.header)
+			}
+
+			for _, ns := range expectedCluster.Namespaces {
+				prefix := render.SanitizeID(ns.Name) + "__"
+				connections := deriver.ServiceToWorkloadConnections(&ns)
+				connections = append(connections, deriver.WorkloadToPVCConnections(&ns)...)
+
+				for _, conn := range connections {
+					edge := tt.edge(prefix+conn.From, prefix+conn.To)
+					if !strings.Contains(output, edge) {
+						t.Errorf("missing edge: %s", edge)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestJSONRenderer_Schema(t *testing.T) {
+	expectedCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.NewJSONRenderer(&buf).Render(expectedCluster); err != nil {
+		t.Fatalf("Failed to render JSON: %v", err)
+	}
+
+	var exported model.Cluster
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	if exported.SchemaVersion != model.SchemaVersion {
+		t.Errorf("expected schema version %s, got %q", model.SchemaVersion, exported.SchemaVersion)
+	}
+
+	if expectedCluster.SchemaVersion != "" || expectedCluster.Edges != nil {
+		t.Errorf("rendering must not modify the input cluster")
+	}
+
+	expectedEdges := render.ClusterEdges(expectedCluster)
+	if len(exported.Edges) != len(expectedEdges) {
+		t.Errorf("expected %d edges, got %d", len(expectedEdges), len(exported.Edges))
+	}
+
+	if len(exported.Namespaces) != 1 || len(exported.Namespaces[0].Deployments) != len(expectedCluster.Namespaces[0].Deployments) {
+		t.Errorf("namespaces did not round-trip: %+v", exported.Namespaces)
+	}
+}
+
+func TestReadSnapshot_RoundTrip(t *testing.T) {
+	expectedCluster, err := loadAndParseAllFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	var snapshot bytes.Buffer
+	if err := render.NewJSONRenderer(&snapshot).Render(expectedCluster); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	restored, err := model.ReadSnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	// A restored snapshot renders exactly like the original topology
+	var original, rerendered bytes.Buffer
+	if err := render.NewD2Renderer(&original, 3).Render(expectedCluster); err != nil {
+		t.Fatalf("Failed to render original: %v", err)
+	}
+	if err := render.NewD2Renderer(&rerendered, 3).Render(restored); err != nil {
+		t.Fatalf("Failed to render snapshot: %v", err)
+	}
+	if original.String() != rerendered.String() {
+		t.Errorf("snapshot renders differently from the original topology")
+	}
+
+	if _, err := model.ReadSnapshot(strings.NewReader(`{"schemaVersion": "k8s-d2/v0"}`)); err == nil {
+		t.Errorf("expected an error for an unsupported schema version")
+	}
+}
+
+func TestD2Renderer_NodeView(t *testing.T) {
+	cluster := &model.Cluster{
+		Nodes: []model.Node{{
+			Name:   "worker-1",
+			Zone:   "eu-west-1a",
+			CPU:    "4",
+			Taints: []model.Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}},
+		}},
+		Namespaces: []model.Namespace{{
+			Name: "shop",
+			Deployments: []model.Workload{{
+				Kind: model.KindDeployment,
+				Name: "api",
+				Pods: []model.Pod{
+					{Name: "api-1", Node: "worker-1"},
+					{Name: "api-2", Node: "worker-1"},
+					{Name: "api-3"},
+				},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	renderer, err := render.New(render.FormatD2, &buf, render.Options{View: render.ViewNodes})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	if err := renderer.Render(cluster); err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"node_worker_1: {",
+		`label: "🖥 worker-1 (eu-west-1a)"`,
+		`cpu: 4\ntaint: dedicated=db:NoSchedule`,
+		"node_worker_1__shop__api: {",
+		`label: "● api (2)\nshop"`,
+		"unscheduled__shop__api: {",
+		`label: "● api (1)\nshop"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("node view missing %q:\n%s", want, output)
+		}
+	}
+
+	if _, err := render.ParseView("racks"); err == nil {
+		t.Error("expected an error for an unknown view")
+	}
+}
+
+func TestD2Renderer_ZoneView(t *testing.T) {
+	cluster := &model.Cluster{
+		Nodes: []model.Node{
+			{Name: "worker-a", Zone: "zone-a"},
+			{Name: "worker-b1", Zone: "zone-b"},
+			{Name: "worker-b2", Zone: "zone-b"},
+		},
+		Namespaces: []model.Namespace{{
+			Name: "shop",
+			Deployments: []model.Workload{
+				{
+					Kind:     model.KindDeployment,
+					Name:     "api",
+					Replicas: 3,
+					Spread:   true,
+					Pods: []model.Pod{
+						{Name: "api-1", Node: "worker-a"},
+						{Name: "api-2", Node: "worker-b1"},
+						{Name: "api-3", Node: "worker-b2"},
+					},
+				},
+				{
+					Kind:     model.KindDeployment,
+					Name:     "cart",
+					Replicas: 2,
+					Pods: []model.Pod{
+						{Name: "cart-1", Node: "worker-b1"},
+						{Name: "cart-2", Node: "worker-b2"},
+					},
+				},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	renderer, err := render.New(render.FormatD2, &buf, render.Options{View: render.ViewZones})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	if err := renderer.Render(cluster); err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"zone_zone_a: {",
+		`label: "📍 zone-b"`,
+		`label: "🖥 worker-b1\n🖥 worker-b2"`,
+		`label: "● api (2/3)\nshop"`,
+		`label: "● cart (2/2)\nshop\n⚠ all replicas in one zone\n⚠ no spread constraints"`,
+		`style.stroke: "#e69500"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("zone view missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "style.stroke:") != 1 {
+		t.Errorf("expected only cart to be flagged:\n%s", output)
+	}
+}
+
+func TestSanitizeID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"api-service", "api_service"},
+		{"api.k8s-d2.test", "api__k8s_d2__test"},
+		{"a--b", "a___2d2d_b"},
+		{"us_east 1", "us___5f_east___20_1"},
+	}
+
+	for _, tt := range tests {
+		if got := render.SanitizeID(tt.name); got != tt.want {
+			t.Errorf("SanitizeID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Valid names that differ only in their separators stay distinct
+	seen := make(map[string]string)
+	for _, name := range []string{"a-b", "a.b", "a--b", "a_b", "a-.b", "a---b", "a.-b"} {
+		id := render.SanitizeID(name)
+		if other, ok := seen[id]; ok {
+			t.Errorf("%q and %q both map to %q", name, other, id)
+		}
+		seen[id] = name
+	}
+}
+
+func TestD2Renderer_DottedNames(t *testing.T) {
+	cluster, err := loadAndParseBaseFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+	w := &cluster.Namespaces[0].Deployments[0]
+	w.ConfigRefs = append(w.ConfigRefs,
+		model.ConfigRef{Kind: model.KindConfigMap, Name: "app.config", Via: []string{model.ViaVolume}},
+		model.ConfigRef{Kind: model.KindSecret, Name: "tls.example.com", Via: []string{model.ViaVolume}},
+	)
+
+	var buf bytes.Buffer
+	if err := render.NewD2Renderer(&buf, 0).Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+	output := buf.String()
+
+	// Dots in names would make D2 paths into nested containers
+	for _, want := range []string{
+		"ing_api__k8s_d2__test: {",
+		"ing_api__k8s_d2__test -> svc_api_service",
+		"cm_app__config: {",
+		render.SanitizeID(w.Name) + " -> cm_app__config",
+		"secret_tls__example__com: {",
+		render.SanitizeID(w.Name) + " -> secret_tls__example__com",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("D2 output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestEscapeD2(t *testing.T) {
+	for in, want := range map[string]string{
+		`say "hi"`:           `say \"hi\"`,
+		"80→8080/TCP\nready": `80→8080/TCP\nready`,
+		`C:\logs\new`:        `C:\\logs\\new`,
+		`/api/v\d+`:          `/api/v\\d+`,
+		`\"` + "\n":          `\\\"\n`,
+	} {
+		if got := render.EscapeD2(in); got != want {
+			t.Errorf("EscapeD2(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestD2Renderer_IngressMissingBackend(t *testing.T) {
+	cluster, err := loadAndParseBaseFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	// legacy-ingress routes to a service the fixtures do not define
+	ns := cluster.Namespaces[0].Name
+	ingress := model.Ref{Kind: model.KindIngress, Namespace: ns, Name: "legacy-ingress"}
+	edges := render.ClusterEdges(cluster)
+	missing := model.Ref{Kind: model.KindService, Namespace: ns, Name: "legacy-service"}
+	if e, ok := findEdge(edges, ingress, missing); !ok || !e.Broken {
+		t.Errorf("expected a broken edge to %s, got %+v", missing.Name, e)
+	}
+	for _, e := range edges {
+		if e.From.Kind == model.KindIngress && e.From != ingress && e.Broken {
+			t.Errorf("expected %s -> %s not to be broken", e.From.Name, e.To.Name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := render.NewD2Renderer(&buf, 0).Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+	output := buf.String()
+
+	// The missing service is drawn as a flagged node, not an implicit one
+	for _, want := range []string{
+		"svc_legacy_service: {",
+		`label: "⎈ legacy-service\n⚠ missing"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("D2 output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestD2Renderer_LegendListsPresentKinds(t *testing.T) {
+	cluster, err := loadAndParseBaseFixtures()
+	if err != nil {
+		t.Fatalf("Failed to load and parse fixtures: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.NewD2Renderer(&buf, 0).Render(cluster); err != nil {
+		t.Fatalf("Failed to render D2: %v", err)
+	}
+	legend, _, _ := strings.Cut(buf.String(), "\n}\n")
+
+	for _, want := range []string{"  ingress: {", "  deployment: {", "  service: {"} {
+		if !strings.Contains(legend, want) {
+			t.Errorf("legend missing %q:\n%s", want, legend)
+		}
+	}
+	// The base fixtures have no gateways, jobs or PVCs
+	for _, unwanted := range []string{"  gateway: {", "  job: {", "  pvc: {", "  ipblock: {"} {
+		if strings.Contains(legend, unwanted) {
+			t.Errorf("legend lists absent kind %q:\n%s", unwanted, legend)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package validation_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+	"github.com/vieitesss/k8s-d2/pkg/server"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServer_Endpoints(t *testing.T) {
+	// A stand-in for the d2 CLI that wraps the D2 source it reads
+	d2 := filepath.Join(t.TempDir(), "d2")
+	script := "#!/bin/sh\necho '<svg>'\ncat\necho '</svg>'\n"
+	if err := os.WriteFile(d2, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write d2 stand-in: %v", err)
+	}
+
+	srv := server.New(server.Options{GridColumns: 3, D2Command: d2})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status, _ := get(t, ts.URL+"/healthz"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before the first topology, got %d", status)
+	}
+
+	client := kube.NewClientFromInterface(fake.NewSimpleClientset(fixtureObjects(t)...))
+	cluster, err := client.FetchTopology(context.Background(), kube.FetchOptions{Namespace: testNamespace})
+	if err != nil {
+		t.Fatalf("Failed to fetch topology: %v", err)
+	}
+	srv.Update(cluster)
+
+	if status, _ := get(t, ts.URL+"/healthz"); status != http.StatusOK {
+		t.Errorf("expected 200 once loaded, got %d", status)
+	}
+
+	status, body := get(t, ts.URL+"/diagram.d2")
+	if status != http.StatusOK || !strings.Contains(body, render.SanitizeID(testNamespace)+": {") {
+		t.Errorf("unexpected D2 response (%d):\n%s", status, body)
+	}
+
+	status, body = get(t, ts.URL+"/topology.json")
+	var doc model.Cluster
+	if err := json.Unmarshal([]byte(body), &doc); err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected JSON response (%d): %v", status, err)
+	}
+	if doc.SchemaVersion != model.SchemaVersion || len(doc.Namespaces) != 1 {
+		t.Errorf("unexpected topology: %+v", doc)
+	}
+
+	status, body = get(t, ts.URL+"/diagram.svg")
+	if status != http.StatusOK || !strings.HasPrefix(body, "<svg>") || !strings.Contains(body, render.SanitizeID(testNamespace)+": {") {
+		t.Errorf("unexpected SVG response (%d):\n%s", status, body)
+	}
+
+	status, body = get(t, ts.URL+"/")
+	if status != http.StatusOK || !strings.Contains(body, `src="diagram.svg"`) || !strings.Contains(body, `http-equiv="refresh"`) || strings.Contains(body, "<script") {
+		t.Errorf("unexpected page (%d):\n%s", status, body)
+	}
+}
+
+func TestServer_WithoutD2(t *testing.T) {
+	srv := server.New(server.Options{D2Command: "k8sdd-missing-d2"})
+	srv.Update(&model.Cluster{Name: "cluster"})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status, _ := get(t, ts.URL+"/diagram.svg"); status != http.StatusNotImplemented {
+		t.Errorf("expected 501 without d2, got %d", status)
+	}
+	if status, body := get(t, ts.URL+"/"); status != http.StatusOK || strings.Contains(body, "diagram.svg") || !strings.Contains(body, "diagram.d2") {
+		t.Errorf("unexpected page without d2 (%d):\n%s", status, body)
+	}
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return resp.StatusCode, string(body)
+}
@@ -79,6 +79,14 @@ func (v *D2Validator) ValidateResources() error {
 			}
 		}
 
+		// Check ingresses
+		for _, ing := range ns.Ingresses {
+			ingID := "ing_" + render.SanitizeID(ing.Name)
+			if !strings.Contains(v.actual, ingID) {
+				return fmt.Errorf("missing ingress: %s", ing.Name)
+			}
+		}
+
 		// Check config node if ConfigMaps or Secrets exist
 		if ns.ConfigMaps > 0 || ns.Secrets > 0 {
 			if !strings.Contains(v.actual, "_config") {
@@ -120,12 +128,16 @@ func (v *D2Validator) ValidateWorkloadLabels() error {
 }
 
 // ValidateServiceConnections checks that service-to-workload connections exist
+// with their port labels
 func (v *D2Validator) ValidateServiceConnections() error {
 	for _, ns := range v.expected.Namespaces {
 		connections := v.deriver.ServiceToWorkloadConnections(&ns)
 
 		for _, conn := range connections {
 			connectionStr := fmt.Sprintf("%s -> %s", conn.From, conn.To)
+			if conn.Label != "" {
+				connectionStr += fmt.Sprintf(": \"%s\"", conn.Label)
+			}
 			if !strings.Contains(v.actual, connectionStr) {
 				return fmt.Errorf("missing expected connection: %s", connectionStr)
 			}
@@ -169,6 +181,21 @@ func (v *D2Validator) ValidatePVCConnections() error {
 	return nil
 }
 
+// ValidateIngressConnections checks that ingress-to-service connections exist
+// with their host/path labels
+func (v *D2Validator) ValidateIngressConnections() error {
+	for _, ns := range v.expected.Namespaces {
+		for _, conn := range v.deriver.IngressToServiceConnections(&ns) {
+			connectionStr := fmt.Sprintf("%s -> %s: \"%s\"", conn.From, conn.To, conn.Label)
+			if !strings.Contains(v.actual, connectionStr) {
+				return fmt.Errorf("missing ingress connection: %s", connectionStr)
+			}
+		}
+	}
+
+	return nil
+}
+
 // ValidateConfigInfo checks that ConfigMap/Secret counts match expected values
 func (v *D2Validator) ValidateConfigInfo() error {
 	for _, ns := range v.expected.Namespaces {
@@ -58,6 +58,12 @@ func TestD2Validator_BasicTopology(t *testing.T) {
 			t.Errorf("Config info validation failed: %v", err)
 		}
 	})
+
+	t.Run("ValidateIngressConnections", func(t *testing.T) {
+		if err := validator.ValidateIngressConnections(); err != nil {
+			t.Errorf("Ingress connection validation failed: %v", err)
+		}
+	})
 }
 
 func TestD2Validator_WithStorage(t *testing.T) {
@@ -111,6 +117,7 @@ var (
 		"04-statefulsets.yaml",
 		"05-daemonsets.yaml",
 		"06-services.yaml",
+		"07-ingress.yaml",
 	}
 
 	storageFixtures = []string{
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/charmbracelet/log"
@@ -13,6 +14,11 @@ func main() {
 	if err := cmd.Execute(version); err != nil {
 		log.SetReportTimestamp(false)
 		log.Error(err.Error())
+
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
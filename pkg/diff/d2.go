@@ -0,0 +1,200 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+// statusStyle holds the D2 colors used for a diff status.
+type statusStyle struct {
+	fill   string
+	stroke string
+}
+
+func styleFor(status Status) statusStyle {
+	switch status {
+	case StatusAdded:
+		return statusStyle{fill: "#d4f7d4", stroke: "#2e7d32"}
+	case StatusRemoved:
+		return statusStyle{fill: "#f9d0d0", stroke: "#c62828"}
+	case StatusChanged:
+		return statusStyle{fill: "#ffe4b3", stroke: "#e69500"}
+	default:
+		return statusStyle{fill: "#f9f9f9", stroke: "#999999"}
+	}
+}
+
+// D2Renderer renders a Result as a D2 diagram of the merged topology, with
+// added resources in green, removed in red and changed in amber.
+type D2Renderer struct {
+	w           io.Writer
+	gridColumns int
+}
+
+func NewD2Renderer(w io.Writer, gridColumns int) *D2Renderer {
+	return &D2Renderer{
+		w:           w,
+		gridColumns: gridColumns,
+	}
+}
+
+func (r *D2Renderer) Render(result *Result) error {
+	if result == nil {
+		return fmt.Errorf("diff result is nil, cannot render")
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by k8s-d2 (diff)\ndirection: right\n\n")
+	r.writeLegend(&b)
+
+	indent := ""
+	if r.gridColumns > 0 {
+		fmt.Fprintf(&b, "namespaces: {\n  grid-columns: %d\n\n", r.gridColumns)
+		indent = "  "
+	}
+
+	// Edges between namespaces are drawn at the root, by their full paths
+	edgesByNamespace := make(map[string][]EdgeDiff)
+	var crossEdges []EdgeDiff
+	for _, e := range result.Edges {
+		if e.Edge.From.Namespace != e.Edge.To.Namespace {
+			crossEdges = append(crossEdges, e)
+			continue
+		}
+		edgesByNamespace[e.Edge.From.Namespace] = append(edgesByNamespace[e.Edge.From.Namespace], e)
+	}
+
+	for _, ns := range result.Namespaces {
+		r.writeNamespace(&b, &ns, edgesByNamespace[ns.Name], indent)
+	}
+
+	container := ""
+	if r.gridColumns > 0 {
+		b.WriteString("}\n")
+		container = "namespaces"
+	}
+
+	for _, e := range crossEdges {
+		r.writeEdgePath(&b, &e, "", render.QualifiedID(e.Edge.From, container), render.QualifiedID(e.Edge.To, container))
+	}
+
+	if _, err := fmt.Fprint(r.w, b.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *D2Renderer) writeNamespace(b *strings.Builder, ns *NamespaceDiff, edges []EdgeDiff, indent string) {
+	style := styleFor(ns.Status)
+	label := ns.Name
+	for _, c := range ns.Changes {
+		label += "\n" + c.String()
+	}
+
+	fmt.Fprintf(b, "%s%s: {\n", indent, render.SanitizeID(ns.Name))
+	fmt.Fprintf(b, "%s  label: \"%s\"\n", indent, render.EscapeD2(label))
+	fmt.Fprintf(b, "%s  grid-columns: 3\n", indent)
+	fmt.Fprintf(b, "%s  style.fill: \"#f0f0f0\"\n", indent)
+	fmt.Fprintf(b, "%s  style.stroke: \"%s\"\n\n", indent, style.stroke)
+
+	for _, rd := range ns.Resources {
+		r.writeResource(b, &rd, indent)
+	}
+
+	for _, e := range edges {
+		r.writeEdge(b, &e, indent)
+	}
+
+	fmt.Fprintf(b, "%s}\n\n", indent)
+}
+
+func (r *D2Renderer) writeResource(b *strings.Builder, rd *ResourceDiff, indent string) {
+	style := styleFor(rd.Status)
+	label := resourceLabel(rd)
+	if rd.Status == StatusAdded || rd.Status == StatusRemoved {
+		label += "\n(" + string(rd.Status) + ")"
+	}
+	for _, c := range rd.Changes {
+		label += "\n" + c.String()
+	}
+
+	fmt.Fprintf(b, "%s  %s: {\n", indent, render.NodeID(rd.Ref))
+	fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, render.EscapeD2(label))
+	fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, style.fill)
+	fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, style.stroke)
+	if rd.Status == StatusRemoved {
+		fmt.Fprintf(b, "%s    style.stroke-dash: 3\n", indent)
+	}
+	fmt.Fprintf(b, "%s  }\n", indent)
+}
+
+func (r *D2Renderer) writeEdge(b *strings.Builder, e *EdgeDiff, indent string) {
+	r.writeEdgePath(b, e, indent+"  ", render.NodeID(e.Edge.From), render.NodeID(e.Edge.To))
+}
+
+// writeEdgePath writes an edge between the D2 paths of its ends.
+func (r *D2Renderer) writeEdgePath(b *strings.Builder, e *EdgeDiff, indent, from, to string) {
+	fmt.Fprintf(b, "%s%s -> %s", indent, from, to)
+	if e.Edge.Label != "" {
+		fmt.Fprintf(b, ": \"%s\"", render.EscapeD2(e.Edge.Label))
+	}
+
+	if e.Status == StatusUnchanged {
+		b.WriteString("\n")
+		return
+	}
+
+	style := styleFor(e.Status)
+	b.WriteString(" {\n")
+	fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, style.stroke)
+	if e.Status == StatusRemoved {
+		fmt.Fprintf(b, "%s    style.stroke-dash: 3\n", indent)
+	}
+	fmt.Fprintf(b, "%s  }\n", indent)
+}
+
+// resourceLabel returns the base label of a resource, matching the labels
+// of the regular D2 diagram.
+func resourceLabel(rd *ResourceDiff) string {
+	switch {
+	case rd.Workload != nil:
+		return fmt.Sprintf("%s %s (%d)", render.WorkloadIcon(rd.Workload.Kind), rd.Workload.Name, rd.Workload.Replicas)
+	case rd.Service != nil:
+		return fmt.Sprintf("⎈ %s\n%s", rd.Service.Name, rd.Service.Type)
+	case rd.PVC != nil:
+		return "💾 " + rd.PVC.Name
+	case rd.Ingress != nil:
+		return "🌐 " + rd.Ingress.Name
+	case rd.Gateway != nil:
+		return "🚪 " + rd.Gateway.Name
+	case rd.Route != nil:
+		return "↪ " + rd.Route.Name
+	case rd.NetworkPolicy != nil:
+		return "🛡 " + rd.NetworkPolicy.Name
+	case rd.PDB != nil:
+		return "🛑 " + rd.PDB.Name
+	default:
+		return rd.Ref.Name
+	}
+}
+
+func (r *D2Renderer) writeLegend(b *strings.Builder) {
+	b.WriteString("legend: {\n")
+	b.WriteString("  label: \"LEGEND\"\n")
+	b.WriteString("  grid-rows: 1\n")
+	b.WriteString("  style.fill: \"#fffacd\"\n\n")
+
+	for _, status := range []Status{StatusAdded, StatusRemoved, StatusChanged, StatusUnchanged} {
+		style := styleFor(status)
+		fmt.Fprintf(b, "  %s: {\n", status)
+		fmt.Fprintf(b, "    label: \"%s\"\n", strings.ToUpper(string(status[:1]))+string(status[1:]))
+		fmt.Fprintf(b, "    style.fill: \"%s\"\n", style.fill)
+		fmt.Fprintf(b, "    style.stroke: \"%s\"\n", style.stroke)
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("}\n\n")
+}
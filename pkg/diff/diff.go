@@ -0,0 +1,570 @@
+// Package diff compares two topologies at the model level, so any input
+// source (live cluster, snapshot, manifests) can be compared with any other.
+package diff
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+// Status describes how a resource differs between two topologies.
+type Status string
+
+const (
+	StatusUnchanged Status = "unchanged"
+	StatusAdded     Status = "added"
+	StatusRemoved   Status = "removed"
+	StatusChanged   Status = "changed"
+)
+
+// Change is a single field that differs on a resource present in both
+// topologies.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s → %s", c.Field, c.Old, c.New)
+}
+
+// ResourceDiff is the comparison result for one resource.
+type ResourceDiff struct {
+	Ref     model.Ref `json:"ref"`
+	Status  Status    `json:"status"`
+	Changes []Change  `json:"changes,omitempty"`
+
+	// Workload, Service, PVC, Ingress, Gateway, Route, NetworkPolicy and PDB
+	// hold the resource as it exists in the new topology, or in the old one
+	// when it was removed. Exactly one is set.
+	Workload *model.Workload `json:"-"`
+	Service  *model.Service  `json:"-"`
+	PVC      *model.PVC      `json:"-"`
+	Ingress  *model.Ingress  `json:"-"`
+	Gateway  *model.Gateway  `json:"-"`
+	Route    *model.Route    `json:"-"`
+
+	NetworkPolicy *model.NetworkPolicy `json:"-"`
+	PDB           *model.PDB           `json:"-"`
+}
+
+// NamespaceDiff groups the resource diffs of one namespace.
+type NamespaceDiff struct {
+	Name      string         `json:"name"`
+	Status    Status         `json:"status"`
+	Changes   []Change       `json:"changes,omitempty"`
+	Resources []ResourceDiff `json:"resources,omitempty"`
+}
+
+// EdgeDiff is the comparison result for one derived relationship.
+type EdgeDiff struct {
+	Edge   model.Edge `json:"edge"`
+	Status Status     `json:"status"`
+}
+
+// Result is the comparison of an old and a new topology. Namespaces and
+// resources from both sides are merged, new ordering first.
+type Result struct {
+	Namespaces []NamespaceDiff `json:"namespaces"`
+	Edges      []EdgeDiff      `json:"edges,omitempty"`
+}
+
+// HasChanges reports whether the topologies differ at all.
+func (r *Result) HasChanges() bool {
+	for _, ns := range r.Namespaces {
+		if ns.Status != StatusUnchanged {
+			return true
+		}
+	}
+	for _, e := range r.Edges {
+		if e.Status != StatusUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare computes the differences from old to new. Jobs created by a
+// CronJob come and go with every run, so only their CronJob is compared.
+func Compare(old, new *model.Cluster) *Result {
+	old, new = withoutCronJobRuns(old), withoutCronJobRuns(new)
+	result := &Result{}
+
+	oldNamespaces := indexNamespaces(old)
+	for _, ns := range new.Namespaces {
+		oldNS, ok := oldNamespaces[ns.Name]
+		if !ok {
+			result.Namespaces = append(result.Namespaces, compareNamespace(nil, &ns))
+			continue
+		}
+		result.Namespaces = append(result.Namespaces, compareNamespace(oldNS, &ns))
+	}
+
+	newNamespaces := indexNamespaces(new)
+	for _, ns := range old.Namespaces {
+		if _, ok := newNamespaces[ns.Name]; !ok {
+			result.Namespaces = append(result.Namespaces, compareNamespace(&ns, nil))
+		}
+	}
+
+	result.Edges = compareEdges(render.ClusterEdges(old), render.ClusterEdges(new))
+	return result
+}
+
+// withoutCronJobRuns returns a copy of the cluster without the Jobs its
+// CronJobs created.
+func withoutCronJobRuns(cluster *model.Cluster) *model.Cluster {
+	stripped := *cluster
+	stripped.Namespaces = make([]model.Namespace, len(cluster.Namespaces))
+	for i, ns := range cluster.Namespaces {
+		ns.Jobs = slices.DeleteFunc(slices.Clone(ns.Jobs), func(w model.Workload) bool {
+			return w.Batch != nil && w.Batch.CronJob != ""
+		})
+		stripped.Namespaces[i] = ns
+	}
+	return &stripped
+}
+
+func indexNamespaces(cluster *model.Cluster) map[string]*model.Namespace {
+	index := make(map[string]*model.Namespace)
+	for i := range cluster.Namespaces {
+		index[cluster.Namespaces[i].Name] = &cluster.Namespaces[i]
+	}
+	return index
+}
+
+// compareNamespace compares two versions of a namespace; either may be nil
+// when the namespace exists on one side only.
+func compareNamespace(old, new *model.Namespace) NamespaceDiff {
+	var status Status
+	switch {
+	case old == nil:
+		old = &model.Namespace{Name: new.Name}
+		status = StatusAdded
+	case new == nil:
+		new = &model.Namespace{Name: old.Name}
+		status = StatusRemoved
+	}
+
+	nd := NamespaceDiff{Name: new.Name}
+	nd.Changes = append(nd.Changes, compareCount("configMaps", old.ConfigMaps, new.ConfigMaps)...)
+	nd.Changes = append(nd.Changes, compareCount("secrets", old.Secrets, new.Secrets)...)
+
+	nd.Resources = append(nd.Resources, compareWorkloads(new.Name, old.AllWorkloads(), new.AllWorkloads())...)
+	nd.Resources = append(nd.Resources, compareServices(new.Name, old.Services, new.Services)...)
+	nd.Resources = append(nd.Resources, comparePVCs(new.Name, old.PVCs, new.PVCs)...)
+	nd.Resources = append(nd.Resources, compareIngresses(new.Name, old.Ingresses, new.Ingresses)...)
+	nd.Resources = append(nd.Resources, compareGateways(new.Name, old.Gateways, new.Gateways)...)
+	nd.Resources = append(nd.Resources, compareRoutes(new.Name, old.Routes, new.Routes)...)
+	nd.Resources = append(nd.Resources, compareNetworkPolicies(new.Name, old.NetworkPolicies, new.NetworkPolicies)...)
+	nd.Resources = append(nd.Resources, comparePDBs(new.Name, old.PDBs, new.PDBs)...)
+
+	nd.Status = status
+	if nd.Status == "" {
+		nd.Status = namespaceStatus(&nd)
+	}
+	return nd
+}
+
+// namespaceStatus reports a namespace present on both sides as changed if
+// its counts or any of its resources changed.
+func namespaceStatus(nd *NamespaceDiff) Status {
+	if len(nd.Changes) > 0 {
+		return StatusChanged
+	}
+	for _, r := range nd.Resources {
+		if r.Status != StatusUnchanged {
+			return StatusChanged
+		}
+	}
+	return StatusUnchanged
+}
+
+func compareWorkloads(nsName string, old, new []model.Workload) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(w model.Workload) model.Ref { return model.Ref{Kind: w.Kind, Name: w.Name} },
+		func(w *model.Workload, rd *ResourceDiff) { rd.Workload = w },
+		func(o, n *model.Workload) []Change {
+			var changes []Change
+			// The replicas of autoscaled workloads move with load; their
+			// range is compared instead
+			if o.Autoscaler == nil || n.Autoscaler == nil {
+				changes = append(changes, compareCount("replicas", o.Replicas, n.Replicas)...)
+			}
+			changes = append(changes, compareString("autoscaling", formatAutoscaler(o.Autoscaler), formatAutoscaler(n.Autoscaler))...)
+			changes = append(changes, compareString("selector", formatWorkloadSelector(o), formatWorkloadSelector(n))...)
+			changes = append(changes, compareString("mounts", formatMounts(o.VolumeMounts), formatMounts(n.VolumeMounts))...)
+			changes = append(changes, compareString("config", formatConfigRefs(o.ConfigRefs), formatConfigRefs(n.ConfigRefs))...)
+			changes = append(changes, compareString("networkAccess", o.NetworkAccess, n.NetworkAccess)...)
+			changes = append(changes, compareString("schedule", formatSchedule(o.Batch), formatSchedule(n.Batch))...)
+			return changes
+		})
+}
+
+func compareServices(nsName string, old, new []model.Service) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(s model.Service) model.Ref { return model.Ref{Kind: model.KindService, Name: s.Name} },
+		func(s *model.Service, rd *ResourceDiff) { rd.Service = s },
+		func(o, n *model.Service) []Change {
+			var changes []Change
+			changes = append(changes, compareString("type", o.Type, n.Type)...)
+			changes = append(changes, compareString("ports", formatPorts(o.Ports), formatPorts(n.Ports))...)
+			changes = append(changes, compareString("selector", formatMap(o.Selector), formatMap(n.Selector))...)
+			changes = append(changes, compareString("externalName", o.ExternalName, n.ExternalName)...)
+			changes = append(changes, compareString("headless", fmt.Sprint(o.Headless), fmt.Sprint(n.Headless))...)
+			// Load balancer addresses are assigned by the cloud provider, so
+			// manifests cannot match them and they are not compared
+			return changes
+		})
+}
+
+func comparePVCs(nsName string, old, new []model.PVC) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(p model.PVC) model.Ref { return model.Ref{Kind: model.KindPVC, Name: p.Name} },
+		func(p *model.PVC, rd *ResourceDiff) { rd.PVC = p },
+		func(o, n *model.PVC) []Change {
+			var changes []Change
+			changes = append(changes, compareString("storageClass", o.StorageClass, n.StorageClass)...)
+			changes = append(changes, compareString("capacity", o.Capacity, n.Capacity)...)
+			return changes
+		})
+}
+
+func compareIngresses(nsName string, old, new []model.Ingress) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(i model.Ingress) model.Ref { return model.Ref{Kind: model.KindIngress, Name: i.Name} },
+		func(i *model.Ingress, rd *ResourceDiff) { rd.Ingress = i },
+		func(o, n *model.Ingress) []Change {
+			var changes []Change
+			changes = append(changes, compareString("class", o.Class, n.Class)...)
+			changes = append(changes, compareString("routes", formatRoutes(o), formatRoutes(n))...)
+			changes = append(changes, compareString("tls", strings.Join(o.TLSSecrets, ","), strings.Join(n.TLSSecrets, ","))...)
+			return changes
+		})
+}
+
+func compareGateways(nsName string, old, new []model.Gateway) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(g model.Gateway) model.Ref { return model.Ref{Kind: model.KindGateway, Name: g.Name} },
+		func(g *model.Gateway, rd *ResourceDiff) { rd.Gateway = g },
+		func(o, n *model.Gateway) []Change {
+			var changes []Change
+			changes = append(changes, compareString("class", o.Class, n.Class)...)
+			changes = append(changes, compareString("listeners", formatListeners(o.Listeners), formatListeners(n.Listeners))...)
+			return changes
+		})
+}
+
+func compareRoutes(nsName string, old, new []model.Route) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(r model.Route) model.Ref { return model.Ref{Kind: r.Kind, Name: r.Name} },
+		func(r *model.Route, rd *ResourceDiff) { rd.Route = r },
+		func(o, n *model.Route) []Change {
+			var changes []Change
+			changes = append(changes, compareString("hostnames", strings.Join(o.Hostnames, ","), strings.Join(n.Hostnames, ","))...)
+			changes = append(changes, compareString("parents", formatParents(o.ParentRefs), formatParents(n.ParentRefs))...)
+			changes = append(changes, compareString("backends", formatRouteBackends(o.Backends), formatRouteBackends(n.Backends))...)
+			return changes
+		})
+}
+
+func compareNetworkPolicies(nsName string, old, new []model.NetworkPolicy) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(p model.NetworkPolicy) model.Ref { return model.Ref{Kind: model.KindNetworkPolicy, Name: p.Name} },
+		func(p *model.NetworkPolicy, rd *ResourceDiff) { rd.NetworkPolicy = p },
+		func(o, n *model.NetworkPolicy) []Change {
+			var changes []Change
+			changes = append(changes, compareString("podSelector", formatSelector(&o.PodSelector), formatSelector(&n.PodSelector))...)
+			changes = append(changes, compareString("policyTypes", strings.Join(o.PolicyTypes, ","), strings.Join(n.PolicyTypes, ","))...)
+			changes = append(changes, compareString("ingress", formatPolicyRules(o.Ingress), formatPolicyRules(n.Ingress))...)
+			changes = append(changes, compareString("egress", formatPolicyRules(o.Egress), formatPolicyRules(n.Egress))...)
+			return changes
+		})
+}
+
+func comparePDBs(nsName string, old, new []model.PDB) []ResourceDiff {
+	return compareByName(nsName, old, new,
+		func(p model.PDB) model.Ref { return model.Ref{Kind: model.KindPDB, Name: p.Name} },
+		func(p *model.PDB, rd *ResourceDiff) { rd.PDB = p },
+		func(o, n *model.PDB) []Change {
+			var changes []Change
+			changes = append(changes, compareString("selector", formatSelector(o.Selector), formatSelector(n.Selector))...)
+			changes = append(changes, compareString("budget", o.Budget(), n.Budget())...)
+			return changes
+		})
+}
+
+// compareByName matches resources by kind and name and reports added,
+// removed and changed ones. ref builds the resource's Ref without
+// namespace, attach stores the resource on the diff, and changes lists the
+// differing fields of a resource present on both sides.
+func compareByName[T any](
+	nsName string,
+	old, new []T,
+	ref func(T) model.Ref,
+	attach func(*T, *ResourceDiff),
+	changes func(o, n *T) []Change,
+) []ResourceDiff {
+	oldIndex := make(map[model.Ref]*T)
+	for i := range old {
+		oldIndex[ref(old[i])] = &old[i]
+	}
+	newIndex := make(map[model.Ref]*T)
+	for i := range new {
+		newIndex[ref(new[i])] = &new[i]
+	}
+
+	var diffs []ResourceDiff
+	for i := range new {
+		r := ref(new[i])
+		rd := ResourceDiff{Ref: model.Ref{Kind: r.Kind, Namespace: nsName, Name: r.Name}, Status: StatusAdded}
+		if o, ok := oldIndex[r]; ok {
+			rd.Changes = changes(o, &new[i])
+			rd.Status = StatusUnchanged
+			if len(rd.Changes) > 0 {
+				rd.Status = StatusChanged
+			}
+		}
+		attach(&new[i], &rd)
+		diffs = append(diffs, rd)
+	}
+
+	for i := range old {
+		r := ref(old[i])
+		if _, ok := newIndex[r]; ok {
+			continue
+		}
+		rd := ResourceDiff{Ref: model.Ref{Kind: r.Kind, Namespace: nsName, Name: r.Name}, Status: StatusRemoved}
+		attach(&old[i], &rd)
+		diffs = append(diffs, rd)
+	}
+
+	return diffs
+}
+
+// compareEdges merges both edge sets. Edge labels are part of the identity,
+// so a changed mount path shows as one removed and one added edge.
+func compareEdges(old, new []model.Edge) []EdgeDiff {
+	oldSet := make(map[model.Edge]bool)
+	for _, e := range old {
+		oldSet[e] = true
+	}
+	newSet := make(map[model.Edge]bool)
+	for _, e := range new {
+		newSet[e] = true
+	}
+
+	var diffs []EdgeDiff
+	for _, e := range new {
+		status := StatusAdded
+		if oldSet[e] {
+			status = StatusUnchanged
+		}
+		diffs = append(diffs, EdgeDiff{Edge: e, Status: status})
+	}
+	for _, e := range old {
+		if !newSet[e] {
+			diffs = append(diffs, EdgeDiff{Edge: e, Status: StatusRemoved})
+		}
+	}
+	return diffs
+}
+
+func compareCount[N int | int32](field string, old, new N) []Change {
+	if old == new {
+		return nil
+	}
+	return []Change{{Field: field, Old: fmt.Sprint(old), New: fmt.Sprint(new)}}
+}
+
+func compareString(field, old, new string) []Change {
+	if old == new {
+		return nil
+	}
+	return []Change{{Field: field, Old: orNone(old), New: orNone(new)}}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// formatMap formats labels as sorted "key=value" pairs.
+func formatMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		pairs = append(pairs, k+"="+m[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// formatPorts formats service ports without their node ports, which the
+// cluster allocates when a manifest leaves them out.
+func formatPorts(ports []model.Port) string {
+	formatted := make([]string, len(ports))
+	for i, p := range ports {
+		p.NodePort = 0
+		formatted[i] = model.FormatPort(p, nil)
+	}
+	return strings.Join(formatted, ",")
+}
+
+// formatRoutes formats an ingress's routes, including the default backend,
+// as "host/path→service:port" entries.
+func formatRoutes(ing *model.Ingress) string {
+	var formatted []string
+	for _, r := range ing.Routes {
+		formatted = append(formatted, model.FormatRoute(r)+"→"+formatBackend(r.Backend))
+	}
+	if ing.DefaultBackend != nil {
+		formatted = append(formatted, "default→"+formatBackend(*ing.DefaultBackend))
+	}
+	return strings.Join(formatted, ",")
+}
+
+func formatBackend(b model.IngressBackend) string {
+	if b.Port == "" {
+		return b.Service
+	}
+	return b.Service + ":" + b.Port
+}
+
+func formatListeners(listeners []model.Listener) string {
+	formatted := make([]string, len(listeners))
+	for i, l := range listeners {
+		formatted[i] = fmt.Sprintf("%s:%s/%d", l.Name, l.Protocol, l.Port)
+		if l.Hostname != "" {
+			formatted[i] += "@" + l.Hostname
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+func formatParents(parents []model.ParentRef) string {
+	formatted := make([]string, len(parents))
+	for i, p := range parents {
+		formatted[i] = p.Namespace + "/" + p.Name
+		if p.SectionName != "" {
+			formatted[i] += "#" + p.SectionName
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+// formatRouteBackends formats route backends as "namespace/service:port=weight"
+// entries, marking the ones denied for lack of a ReferenceGrant.
+func formatRouteBackends(backends []model.RouteBackend) string {
+	formatted := make([]string, len(backends))
+	for i, b := range backends {
+		formatted[i] = fmt.Sprintf("%s/%s:%d=%d", b.Namespace, b.Service, b.Port, b.Weight)
+		if b.Denied {
+			formatted[i] += "(denied)"
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+// formatAutoscaler formats the kind and replica range of an autoscaler,
+// leaving out current metric values.
+func formatAutoscaler(a *model.Autoscaler) string {
+	if a == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s %d..%d", a.Kind, a.Name, a.MinReplicas, a.MaxReplicas)
+}
+
+// formatConfigRefs formats ConfigMap and Secret references as "Kind/name"
+// entries, marking the ones to missing objects.
+func formatConfigRefs(refs []model.ConfigRef) string {
+	formatted := make([]string, len(refs))
+	for i, ref := range refs {
+		formatted[i] = ref.Kind + "/" + ref.Name
+		if ref.Missing {
+			formatted[i] += "(missing)"
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+// formatSchedule formats the desired schedule of a batch workload, leaving
+// out run state that changes with every run.
+func formatSchedule(batch *model.Batch) string {
+	if batch == nil || batch.Schedule == "" {
+		return ""
+	}
+	if batch.Suspend {
+		return batch.Schedule + " (suspended)"
+	}
+	return batch.Schedule
+}
+
+// formatWorkloadSelector formats the full selector of a workload, falling
+// back to its labels for CronJobs, Jobs and older snapshots.
+func formatWorkloadSelector(w *model.Workload) string {
+	if w.Selector == nil {
+		return formatMap(w.Labels)
+	}
+	return formatSelector(w.Selector)
+}
+
+// formatSelector formats a label selector as its matchLabels followed by
+// "key operator (values)" expressions. A nil selector formats as "".
+func formatSelector(s *model.LabelSelector) string {
+	if s == nil {
+		return ""
+	}
+	formatted := []string{formatMap(s.MatchLabels)}
+	for _, r := range s.MatchExpressions {
+		formatted = append(formatted, fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ",")))
+	}
+	return strings.Join(formatted, ";")
+}
+
+// formatPolicyRules formats policy rules as "peers:ports" entries.
+func formatPolicyRules(rules []model.PolicyRule) string {
+	formatted := make([]string, len(rules))
+	for i, rule := range rules {
+		peers := make([]string, len(rule.Peers))
+		for j, peer := range rule.Peers {
+			switch {
+			case peer.IPBlock != nil:
+				peers[j] = "ipBlock=" + peer.IPBlock.CIDR
+				if len(peer.IPBlock.Except) > 0 {
+					peers[j] += " except " + strings.Join(peer.IPBlock.Except, ",")
+				}
+			default:
+				var parts []string
+				if peer.NamespaceSelector != nil {
+					parts = append(parts, "namespaces["+formatSelector(peer.NamespaceSelector)+"]")
+				}
+				if peer.PodSelector != nil {
+					parts = append(parts, "pods["+formatSelector(peer.PodSelector)+"]")
+				}
+				peers[j] = strings.Join(parts, " ")
+			}
+		}
+		ports := make([]string, len(rule.Ports))
+		for j, port := range rule.Ports {
+			ports[j] = model.FormatPolicyPort(port)
+		}
+		formatted[i] = strings.Join(peers, "|") + ":" + strings.Join(ports, "|")
+	}
+	return strings.Join(formatted, ",")
+}
+
+func formatMounts(mounts []model.VolumeMount) string {
+	formatted := make([]string, len(mounts))
+	for i, m := range mounts {
+		formatted[i] = m.PVCName + ":" + model.FormatMount(m)
+	}
+	slices.Sort(formatted)
+	return strings.Join(formatted, ",")
+}
@@ -0,0 +1,179 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// DriftReport lists how a live topology deviates from an expected one.
+type DriftReport struct {
+	Missing    []model.Ref `json:"missing"`    // expected but not live
+	Extra      []model.Ref `json:"extra"`      // live but not expected
+	Mismatched []Mismatch  `json:"mismatched"` // present on both sides with different fields
+}
+
+// Mismatch is a resource whose live fields differ from the expected ones.
+type Mismatch struct {
+	Ref    model.Ref    `json:"ref"`
+	Fields []FieldDrift `json:"fields"`
+}
+
+// FieldDrift is a single field whose live value differs from the expected
+// one.
+type FieldDrift struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Live     string `json:"live"`
+}
+
+// Drift compares a live topology against the expected one. Only namespaces
+// present in the expected topology are compared, so manifests covering part
+// of a cluster do not report every other namespace as extra.
+func Drift(expected, live *model.Cluster) *DriftReport {
+	expectedNamespaces := indexNamespaces(expected)
+	scoped := &model.Cluster{Name: live.Name}
+	for _, ns := range live.Namespaces {
+		if expectedNS, ok := expectedNamespaces[ns.Name]; ok {
+			ns = specOnly(ns)
+			defaultStorageClasses(expectedNS, &ns)
+			scoped.Namespaces = append(scoped.Namespaces, ns)
+		}
+	}
+	spec := &model.Cluster{Name: expected.Name}
+	for _, ns := range expected.Namespaces {
+		spec.Namespaces = append(spec.Namespaces, specOnly(ns))
+	}
+
+	report := &DriftReport{
+		Missing:    []model.Ref{},
+		Extra:      []model.Ref{},
+		Mismatched: []Mismatch{},
+	}
+	for _, ns := range Compare(spec, scoped).Namespaces {
+		for _, rd := range ns.Resources {
+			report.add(&rd)
+		}
+	}
+	return report
+}
+
+// specOnly returns a copy of a namespace without the fields a cluster fills
+// in from status, which manifests cannot state: the desired pods of a
+// DaemonSet follow the nodes it is scheduled on, and a claim may be bound
+// to more than it requested, so its requested size is compared instead.
+func specOnly(ns model.Namespace) model.Namespace {
+	ns.DaemonSets = slices.Clone(ns.DaemonSets)
+	for i := range ns.DaemonSets {
+		ns.DaemonSets[i].Replicas = 0
+	}
+	ns.PVCs = slices.Clone(ns.PVCs)
+	for i := range ns.PVCs {
+		if ns.PVCs[i].Requested != "" {
+			ns.PVCs[i].Capacity = ns.PVCs[i].Requested
+		}
+	}
+	return ns
+}
+
+// defaultStorageClasses clears the storage class of the live claims whose
+// expected claim leaves it out, as the cluster fills in its default class.
+func defaultStorageClasses(expected, live *model.Namespace) {
+	for i := range live.PVCs {
+		pvc := &live.PVCs[i]
+		j := slices.IndexFunc(expected.PVCs, func(p model.PVC) bool { return p.Name == pvc.Name })
+		if j >= 0 && expected.PVCs[j].StorageClass == "" {
+			pvc.StorageClass = ""
+		}
+	}
+}
+
+func (r *DriftReport) add(rd *ResourceDiff) {
+	switch rd.Status {
+	case StatusRemoved:
+		r.Missing = append(r.Missing, rd.Ref)
+	case StatusAdded:
+		r.Extra = append(r.Extra, rd.Ref)
+	case StatusChanged:
+		m := Mismatch{Ref: rd.Ref}
+		for _, c := range rd.Changes {
+			m.Fields = append(m.Fields, FieldDrift{Field: c.Field, Expected: c.Old, Live: c.New})
+		}
+		r.Mismatched = append(r.Mismatched, m)
+	}
+}
+
+// HasDrift reports whether any resource is missing, extra or mismatched.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Missing)+len(r.Extra)+len(r.Mismatched) > 0
+}
+
+// Summary returns a one-line count of the drifted resources.
+func (r *DriftReport) Summary() string {
+	return fmt.Sprintf("%d missing, %d extra, %d mismatched", len(r.Missing), len(r.Extra), len(r.Mismatched))
+}
+
+// WriteText writes a human-readable report.
+func (r *DriftReport) WriteText(w io.Writer) error {
+	if !r.HasDrift() {
+		_, err := fmt.Fprintln(w, "No drift detected")
+		return err
+	}
+
+	ew := &errWriter{w: w}
+	ew.printf("Drift detected: %s\n", r.Summary())
+
+	if len(r.Missing) > 0 {
+		ew.printf("\nMissing (expected, not in cluster):\n")
+		for _, ref := range r.Missing {
+			ew.printf("  - %s\n", formatRef(ref))
+		}
+	}
+
+	if len(r.Extra) > 0 {
+		ew.printf("\nExtra (in cluster, not expected):\n")
+		for _, ref := range r.Extra {
+			ew.printf("  + %s\n", formatRef(ref))
+		}
+	}
+
+	if len(r.Mismatched) > 0 {
+		ew.printf("\nMismatched:\n")
+		for _, m := range r.Mismatched {
+			ew.printf("  ~ %s\n", formatRef(m.Ref))
+			for _, f := range m.Fields {
+				ew.printf("      %s: expected %s, live %s\n", f.Field, f.Expected, f.Live)
+			}
+		}
+	}
+
+	return ew.err
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *DriftReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func formatRef(ref model.Ref) string {
+	return fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+}
+
+// errWriter keeps the first write error so a report can be written without
+// checking every line.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
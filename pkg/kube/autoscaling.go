@@ -0,0 +1,240 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// KEDAGroup is the API group of the KEDA CRDs.
+const KEDAGroup = "keda.sh"
+
+// KEDA defaults for ScaledObjects that leave the replica range unset.
+const (
+	kedaMinReplicas = 0
+	kedaMaxReplicas = 100
+)
+
+// discoverKEDA returns the ScaledObject resource when KEDA is installed.
+// Like discoverGatewayAPI, discovery errors are treated as "not installed".
+func discoverKEDA(disc discovery.DiscoveryInterface) map[string]schema.GroupVersionResource {
+	gvrs := make(map[string]schema.GroupVersionResource)
+	list, err := disc.ServerResourcesForGroupVersion(KEDAGroup + "/v1alpha1")
+	if err != nil {
+		return gvrs
+	}
+	for _, r := range list.APIResources {
+		if r.Name == "scaledobjects" {
+			gvrs[model.KindScaledObject] = schema.GroupVersionResource{Group: KEDAGroup, Version: "v1alpha1", Resource: r.Name}
+		}
+	}
+	return gvrs
+}
+
+// ConvertHPA converts an autoscaling/v2 HorizontalPodAutoscaler to a
+// model.Autoscaler, pairing each metric target with its current value.
+func ConvertHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) model.Autoscaler {
+	// Default to 1 replica if not specified (Kubernetes HPA default)
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+
+	a := model.Autoscaler{
+		Kind:        model.KindHPA,
+		Name:        hpa.Name,
+		MinReplicas: minReplicas,
+		MaxReplicas: hpa.Spec.MaxReplicas,
+	}
+	for _, metric := range hpa.Spec.Metrics {
+		name, target := hpaMetricTarget(metric)
+		if name == "" {
+			continue
+		}
+		if current := hpaMetricCurrent(hpa.Status.CurrentMetrics, metric.Type, name); current != "" {
+			a.Metrics = append(a.Metrics, fmt.Sprintf("%s %s / %s", name, current, target))
+			continue
+		}
+		a.Metrics = append(a.Metrics, fmt.Sprintf("%s target %s", name, target))
+	}
+	return a
+}
+
+// hpaMetricTarget returns the name and formatted target of a metric spec.
+func hpaMetricTarget(metric autoscalingv2.MetricSpec) (string, string) {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource != nil {
+			return string(metric.Resource.Name), formatMetricTarget(metric.Resource.Target)
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if metric.ContainerResource != nil {
+			name := fmt.Sprintf("%s (%s)", metric.ContainerResource.Name, metric.ContainerResource.Container)
+			return name, formatMetricTarget(metric.ContainerResource.Target)
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods != nil {
+			return metric.Pods.Metric.Name, formatMetricTarget(metric.Pods.Target)
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object != nil {
+			return metric.Object.Metric.Name, formatMetricTarget(metric.Object.Target)
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External != nil {
+			return metric.External.Metric.Name, formatMetricTarget(metric.External.Target)
+		}
+	}
+	return "", ""
+}
+
+// hpaMetricCurrent returns the formatted current value of a metric, or ""
+// when the HPA has not observed it yet.
+func hpaMetricCurrent(statuses []autoscalingv2.MetricStatus, kind autoscalingv2.MetricSourceType, name string) string {
+	for _, status := range statuses {
+		if status.Type != kind {
+			continue
+		}
+		var statusName string
+		var current autoscalingv2.MetricValueStatus
+		switch {
+		case status.Resource != nil:
+			statusName, current = string(status.Resource.Name), status.Resource.Current
+		case status.ContainerResource != nil:
+			statusName = fmt.Sprintf("%s (%s)", status.ContainerResource.Name, status.ContainerResource.Container)
+			current = status.ContainerResource.Current
+		case status.Pods != nil:
+			statusName, current = status.Pods.Metric.Name, status.Pods.Current
+		case status.Object != nil:
+			statusName, current = status.Object.Metric.Name, status.Object.Current
+		case status.External != nil:
+			statusName, current = status.External.Metric.Name, status.External.Current
+		}
+		if statusName == name {
+			return formatMetricValue(current.AverageUtilization, current.AverageValue, current.Value)
+		}
+	}
+	return ""
+}
+
+func formatMetricTarget(target autoscalingv2.MetricTarget) string {
+	return formatMetricValue(target.AverageUtilization, target.AverageValue, target.Value)
+}
+
+// formatMetricValue formats a utilization as a percentage, or else an
+// average or absolute quantity.
+func formatMetricValue(utilization *int32, average, value *resource.Quantity) string {
+	switch {
+	case utilization != nil:
+		return fmt.Sprintf("%d%%", *utilization)
+	case average != nil:
+		return average.String()
+	case value != nil:
+		return value.String()
+	default:
+		return "?"
+	}
+}
+
+// AttachHPA sets an HPA as the autoscaler of the workload it targets. HPAs
+// that KEDA manages for a ScaledObject are skipped, since the ScaledObject
+// is shown instead.
+func AttachHPA(ns *model.Namespace, hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	if owner := metav1.GetControllerOf(hpa); owner != nil && owner.Kind == model.KindScaledObject {
+		return
+	}
+	target := model.Ref{Kind: hpa.Spec.ScaleTargetRef.Kind, Namespace: ns.Name, Name: hpa.Spec.ScaleTargetRef.Name}
+	AttachAutoscaler(ns, target, ConvertHPA(hpa))
+}
+
+// AttachAutoscaler sets the autoscaler of a workload. Autoscalers whose
+// target is not in the namespace are dropped.
+func AttachAutoscaler(ns *model.Namespace, target model.Ref, a model.Autoscaler) {
+	if w := ns.Workload(target.Kind, target.Name); w != nil {
+		w.Autoscaler = &a
+	}
+}
+
+// ConvertScaledObject converts a KEDA ScaledObject to a model.Autoscaler
+// and returns the workload it targets. KEDA scales Deployments unless told
+// otherwise, and can scale down to zero replicas.
+func ConvertScaledObject(obj *unstructured.Unstructured) (model.Autoscaler, model.Ref, error) {
+	a := model.Autoscaler{
+		Kind:        model.KindScaledObject,
+		Name:        obj.GetName(),
+		MinReplicas: kedaMinReplicas,
+		MaxReplicas: kedaMaxReplicas,
+	}
+	target := model.Ref{Kind: model.KindDeployment, Namespace: obj.GetNamespace()}
+
+	var err error
+	if target.Name, _, err = unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "name"); err != nil {
+		return a, target, err
+	}
+	if kind, _, err := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "kind"); err != nil {
+		return a, target, err
+	} else if kind != "" {
+		target.Kind = kind
+	}
+	if n, ok, err := unstructured.NestedInt64(obj.Object, "spec", "minReplicaCount"); err != nil {
+		return a, target, err
+	} else if ok {
+		a.MinReplicas = int32(n)
+	}
+	if n, ok, err := unstructured.NestedInt64(obj.Object, "spec", "maxReplicaCount"); err != nil {
+		return a, target, err
+	} else if ok {
+		a.MaxReplicas = int32(n)
+	}
+
+	triggers, _, err := unstructured.NestedSlice(obj.Object, "spec", "triggers")
+	if err != nil {
+		return a, target, err
+	}
+	for _, trigger := range triggers {
+		t, ok := trigger.(map[string]any)
+		if !ok {
+			continue
+		}
+		if kind, ok := t["type"].(string); ok && kind != "" {
+			a.Metrics = append(a.Metrics, kind+" trigger")
+		}
+	}
+	return a, target, nil
+}
+
+// fetchAutoscalers attaches HPAs, when the client may list them, then
+// ScaledObjects when KEDA is installed, to the workloads fetched before it.
+func (c *Client) fetchAutoscalers(ctx context.Context, keda map[string]schema.GroupVersionResource, nsName string, ns *model.Namespace) error {
+	hpas, err := c.lister.hpas(ctx, nsName)
+	if err := skipUnavailable(err, resourceAutoscaler, nsName); err != nil {
+		return err
+	}
+	for _, hpa := range hpas {
+		AttachHPA(ns, hpa)
+	}
+
+	gvr, ok := keda[model.KindScaledObject]
+	if !ok {
+		return nil
+	}
+	objects, err := c.lister.customObjects(ctx, gvr, nsName)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		a, target, err := ConvertScaledObject(obj)
+		if err != nil {
+			return err
+		}
+		AttachAutoscaler(ns, target, a)
+	}
+	return nil
+}
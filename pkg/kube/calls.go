@@ -0,0 +1,29 @@
+package kube
+
+import (
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ExtractServiceCalls lists the services the env values of a pod spec name
+// by cluster DNS name, with the variables naming each.
+func ExtractServiceCalls(spec *corev1.PodSpec) []model.ServiceCall {
+	var calls []model.ServiceCall
+	for _, container := range slices.Concat(spec.InitContainers, spec.Containers) {
+		for _, env := range container.Env {
+			for _, ref := range model.ServiceDNSNames(env.Value) {
+				i := slices.IndexFunc(calls, func(c model.ServiceCall) bool { return c.Namespace == ref.Namespace && c.Service == ref.Name })
+				if i < 0 {
+					calls = append(calls, model.ServiceCall{Namespace: ref.Namespace, Service: ref.Name})
+					i = len(calls) - 1
+				}
+				if !slices.Contains(calls[i].Env, env.Name) {
+					calls[i].Env = append(calls[i].Env, env.Name)
+				}
+			}
+		}
+	}
+	return calls
+}
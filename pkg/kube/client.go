@@ -3,16 +3,24 @@ package kube
 import (
 	"flag"
 	"io"
-	"path/filepath"
+	"maps"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
 )
 
 type Client struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	lister    resourceLister
+
+	// customAPI pins the CRD-backed resources (Gateway API, KEDA) to list.
+	// When nil they are discovered on every fetch, so CRDs installed later
+	// are picked up.
+	customAPI map[string]schema.GroupVersionResource
 }
 
 func init() {
@@ -26,13 +34,23 @@ func init() {
 }
 
 func NewClient(kubeconfigPath string) (*Client, error) {
-	if kubeconfigPath == "" {
-		if home := homedir.HomeDir(); home != "" {
-			kubeconfigPath = filepath.Join(home, ".kube", "config")
-		}
+	return NewClientForContext(kubeconfigPath, "")
+}
+
+// NewClientForContext creates a client for a named kubeconfig context. An
+// empty context name uses the kubeconfig's current context. Without an
+// explicit kubeconfig path, $KUBECONFIG and ~/.kube/config are tried, then
+// the in-cluster service account.
+func NewClientForContext(kubeconfigPath, contextName string) (*Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		rules.ExplicitPath = kubeconfigPath
 	}
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{CurrentContext: contextName},
+	).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -42,5 +60,40 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{clientset: clientset}, nil
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClientFromInterfaces(clientset, dyn), nil
+}
+
+// NewClientFromInterface wraps an existing clientset, such as a fake one in
+// tests. Without a dynamic client, CRD-backed resources are not fetched.
+func NewClientFromInterface(clientset kubernetes.Interface) *Client {
+	return NewClientFromInterfaces(clientset, nil)
+}
+
+// NewClientFromInterfaces wraps an existing clientset and dynamic client,
+// which is used for CRD-backed resources such as the Gateway API and KEDA.
+func NewClientFromInterfaces(clientset kubernetes.Interface, dyn dynamic.Interface) *Client {
+	return &Client{
+		clientset: clientset,
+		dynamic:   dyn,
+		lister:    apiLister{clientset: clientset, dynamic: dyn},
+	}
+}
+
+// customResources returns the CRD-backed resources to list by kind, or nil
+// when there is no dynamic client to list them with.
+func (c *Client) customResources() map[string]schema.GroupVersionResource {
+	if c.customAPI != nil {
+		return c.customAPI
+	}
+	if c.dynamic == nil {
+		return nil
+	}
+	gvrs := discoverGatewayAPI(c.clientset.Discovery())
+	maps.Copy(gvrs, discoverKEDA(c.clientset.Discovery()))
+	return gvrs
 }
@@ -0,0 +1,86 @@
+package kube
+
+import (
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// configRefs collects the ConfigMaps and Secrets of a pod spec in order of
+// first use.
+type configRefs []model.ConfigRef
+
+// add records one use of an object. optional is the use's optional field;
+// nil means required.
+func (c *configRefs) add(kind, name, via string, optional *bool) {
+	if name == "" {
+		return
+	}
+	opt := optional != nil && *optional
+	i := slices.IndexFunc(*c, func(ref model.ConfigRef) bool { return ref.Kind == kind && ref.Name == name })
+	if i < 0 {
+		*c = append(*c, model.ConfigRef{Kind: kind, Name: name, Via: []string{via}, Optional: opt})
+		return
+	}
+	ref := &(*c)[i]
+	if !slices.Contains(ref.Via, via) {
+		ref.Via = append(ref.Via, via)
+	}
+	ref.Optional = ref.Optional && opt
+}
+
+// ExtractConfigRefs lists the ConfigMaps and Secrets a pod spec uses
+// through volumes (including projected ones), env, envFrom and
+// imagePullSecrets, in init containers as well as containers.
+func ExtractConfigRefs(spec *corev1.PodSpec) []model.ConfigRef {
+	var refs configRefs
+
+	for _, vol := range spec.Volumes {
+		if cm := vol.ConfigMap; cm != nil {
+			refs.add(model.KindConfigMap, cm.Name, model.ViaVolume, cm.Optional)
+		}
+		if secret := vol.Secret; secret != nil {
+			refs.add(model.KindSecret, secret.SecretName, model.ViaVolume, secret.Optional)
+		}
+		if vol.Projected == nil {
+			continue
+		}
+		for _, source := range vol.Projected.Sources {
+			if cm := source.ConfigMap; cm != nil {
+				refs.add(model.KindConfigMap, cm.Name, model.ViaVolume, cm.Optional)
+			}
+			if secret := source.Secret; secret != nil {
+				refs.add(model.KindSecret, secret.Name, model.ViaVolume, secret.Optional)
+			}
+		}
+	}
+
+	for _, container := range slices.Concat(spec.InitContainers, spec.Containers) {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if key := env.ValueFrom.ConfigMapKeyRef; key != nil {
+				refs.add(model.KindConfigMap, key.Name, model.ViaEnv, key.Optional)
+			}
+			if key := env.ValueFrom.SecretKeyRef; key != nil {
+				refs.add(model.KindSecret, key.Name, model.ViaEnv, key.Optional)
+			}
+		}
+		for _, from := range container.EnvFrom {
+			if cm := from.ConfigMapRef; cm != nil {
+				refs.add(model.KindConfigMap, cm.Name, model.ViaEnvFrom, cm.Optional)
+			}
+			if secret := from.SecretRef; secret != nil {
+				refs.add(model.KindSecret, secret.Name, model.ViaEnvFrom, secret.Optional)
+			}
+		}
+	}
+
+	for _, secret := range spec.ImagePullSecrets {
+		refs.add(model.KindSecret, secret.Name, model.ViaImagePullSecret, nil)
+	}
+
+	return refs
+}
@@ -0,0 +1,489 @@
+package kube
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ConvertDeployment converts a Kubernetes Deployment to a model.Workload.
+func ConvertDeployment(d *appsv1.Deployment) model.Workload {
+	// Default to 1 replica if not specified (Kubernetes Deployment default)
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	return model.Workload{
+		Name:      d.Name,
+		Kind:      model.KindDeployment,
+		Replicas:  replicas,
+		Labels:    selectorLabels(d.Spec.Selector),
+		PodLabels: d.Spec.Template.Labels,
+		Selector:  optionalSelector(d.Spec.Selector),
+		VolumeMounts: ExtractVolumeMounts(
+			d.Spec.Template.Spec.Containers,
+			d.Spec.Template.Spec.Volumes,
+		),
+		ContainerPorts: ExtractContainerPorts(&d.Spec.Template.Spec),
+		ConfigRefs:     ExtractConfigRefs(&d.Spec.Template.Spec),
+		ServiceCalls:   ExtractServiceCalls(&d.Spec.Template.Spec),
+		Spread:         spreadsReplicas(&d.Spec.Template.Spec),
+	}
+}
+
+// ConvertStatefulSet converts a Kubernetes StatefulSet to a model.Workload,
+// including the PVCs generated from its volumeClaimTemplates.
+func ConvertStatefulSet(ss *appsv1.StatefulSet) model.Workload {
+	// Default to 1 replica if not specified (Kubernetes StatefulSet default)
+	replicas := int32(1)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+
+	return model.Workload{
+		Name:      ss.Name,
+		Kind:      model.KindStatefulSet,
+		Replicas:  replicas,
+		Labels:    selectorLabels(ss.Spec.Selector),
+		PodLabels: ss.Spec.Template.Labels,
+		Selector:  optionalSelector(ss.Spec.Selector),
+		VolumeMounts: ExtractAllStatefulSetVolumeMounts(
+			ss.Spec.Template.Spec.Containers,
+			ss.Spec.Template.Spec.Volumes,
+			ss.Spec.VolumeClaimTemplates,
+			ss.Name,
+			replicas,
+		),
+		ContainerPorts: ExtractContainerPorts(&ss.Spec.Template.Spec),
+		ConfigRefs:     ExtractConfigRefs(&ss.Spec.Template.Spec),
+		ServiceCalls:   ExtractServiceCalls(&ss.Spec.Template.Spec),
+		Spread:         spreadsReplicas(&ss.Spec.Template.Spec),
+	}
+}
+
+// spreadsReplicas reports whether a pod spec asks the scheduler to keep
+// replicas apart, through topology spread constraints or pod anti-affinity.
+func spreadsReplicas(spec *corev1.PodSpec) bool {
+	if len(spec.TopologySpreadConstraints) > 0 {
+		return true
+	}
+	if spec.Affinity == nil || spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	anti := spec.Affinity.PodAntiAffinity
+	return len(anti.RequiredDuringSchedulingIgnoredDuringExecution) > 0 ||
+		len(anti.PreferredDuringSchedulingIgnoredDuringExecution) > 0
+}
+
+// ConvertDaemonSet converts a Kubernetes DaemonSet to a model.Workload.
+// DaemonSets have no fixed replica count, so the scheduled count from the
+// status is used (zero for objects that were never applied).
+func ConvertDaemonSet(ds *appsv1.DaemonSet) model.Workload {
+	return model.Workload{
+		Name:      ds.Name,
+		Kind:      model.KindDaemonSet,
+		Replicas:  ds.Status.DesiredNumberScheduled,
+		Labels:    selectorLabels(ds.Spec.Selector),
+		PodLabels: ds.Spec.Template.Labels,
+		Selector:  optionalSelector(ds.Spec.Selector),
+		VolumeMounts: ExtractVolumeMounts(
+			ds.Spec.Template.Spec.Containers,
+			ds.Spec.Template.Spec.Volumes,
+		),
+		ContainerPorts: ExtractContainerPorts(&ds.Spec.Template.Spec),
+		ConfigRefs:     ExtractConfigRefs(&ds.Spec.Template.Spec),
+		ServiceCalls:   ExtractServiceCalls(&ds.Spec.Template.Spec),
+	}
+}
+
+// ConvertCronJob converts a Kubernetes CronJob to a model.Workload. Its
+// replicas are the parallelism of the jobs it creates, and the last run is
+// derived from the schedule and success times in its status.
+func ConvertCronJob(cj *batchv1.CronJob) model.Workload {
+	template := cj.Spec.JobTemplate.Spec
+
+	batch := &model.Batch{
+		Schedule: cj.Spec.Schedule,
+		Suspend:  cj.Spec.Suspend != nil && *cj.Spec.Suspend,
+		Active:   int32(len(cj.Status.Active)),
+	}
+	switch status := cj.Status; {
+	case batch.Active > 0:
+		batch.LastRun = model.RunRunning
+	case status.LastScheduleTime == nil:
+		// Never scheduled
+	case status.LastSuccessfulTime != nil && !status.LastSuccessfulTime.Before(status.LastScheduleTime):
+		batch.LastRun = model.RunSucceeded
+	default:
+		batch.LastRun = model.RunFailed
+	}
+
+	return model.Workload{
+		Name:      cj.Name,
+		Kind:      model.KindCronJob,
+		Replicas:  jobParallelism(&template),
+		Labels:    template.Template.Labels,
+		PodLabels: template.Template.Labels,
+		VolumeMounts: ExtractVolumeMounts(
+			template.Template.Spec.Containers,
+			template.Template.Spec.Volumes,
+		),
+		ContainerPorts: ExtractContainerPorts(&template.Template.Spec),
+		ConfigRefs:     ExtractConfigRefs(&template.Template.Spec),
+		ServiceCalls:   ExtractServiceCalls(&template.Template.Spec),
+		Batch:          batch,
+	}
+}
+
+// ConvertJob converts a Kubernetes Job to a model.Workload, recording the
+// CronJob that created it, if any. Job selectors are generated by the API
+// server, so the pod template labels are used instead.
+func ConvertJob(job *batchv1.Job) model.Workload {
+	batch := &model.Batch{
+		Suspend: job.Spec.Suspend != nil && *job.Spec.Suspend,
+		Active:  job.Status.Active,
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == model.KindCronJob {
+		batch.CronJob = owner.Name
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			batch.LastRun = model.RunSucceeded
+		case batchv1.JobFailed:
+			batch.LastRun = model.RunFailed
+		}
+	}
+	if batch.LastRun == "" && batch.Active > 0 {
+		batch.LastRun = model.RunRunning
+	}
+
+	return model.Workload{
+		Name:      job.Name,
+		Kind:      model.KindJob,
+		Replicas:  jobParallelism(&job.Spec),
+		Labels:    job.Spec.Template.Labels,
+		PodLabels: job.Spec.Template.Labels,
+		VolumeMounts: ExtractVolumeMounts(
+			job.Spec.Template.Spec.Containers,
+			job.Spec.Template.Spec.Volumes,
+		),
+		ContainerPorts: ExtractContainerPorts(&job.Spec.Template.Spec),
+		ConfigRefs:     ExtractConfigRefs(&job.Spec.Template.Spec),
+		ServiceCalls:   ExtractServiceCalls(&job.Spec.Template.Spec),
+		Batch:          batch,
+	}
+}
+
+// ExtractContainerPorts lists the ports declared by the containers of a
+// pod spec.
+func ExtractContainerPorts(spec *corev1.PodSpec) []model.ContainerPort {
+	var ports []model.ContainerPort
+	for _, container := range spec.Containers {
+		for _, p := range container.Ports {
+			ports = append(ports, model.ContainerPort{
+				Name:     p.Name,
+				Port:     p.ContainerPort,
+				Protocol: protocolOrTCP(p.Protocol),
+			})
+		}
+	}
+	return ports
+}
+
+// protocolOrTCP returns the protocol of a port, which defaults to TCP.
+func protocolOrTCP(protocol corev1.Protocol) string {
+	if protocol == "" {
+		return string(corev1.ProtocolTCP)
+	}
+	return string(protocol)
+}
+
+// jobParallelism returns how many pods a job runs at once, defaulting to 1
+// like the API server.
+func jobParallelism(spec *batchv1.JobSpec) int32 {
+	if spec.Parallelism != nil {
+		return *spec.Parallelism
+	}
+	return 1
+}
+
+// ConvertService converts a Kubernetes Service to a model.Service.
+func ConvertService(svc *corev1.Service) model.Service {
+	ports := []model.Port{}
+	for _, p := range svc.Spec.Ports {
+		port := model.Port{
+			Name:     p.Name,
+			Port:     p.Port,
+			Protocol: protocolOrTCP(p.Protocol),
+			NodePort: p.NodePort,
+		}
+		switch {
+		case p.TargetPort.Type == intstr.String:
+			port.TargetPortName = p.TargetPort.StrVal
+		case p.TargetPort.IntVal == 0:
+			// targetPort defaults to port when omitted from a manifest
+			port.TargetPort = p.Port
+		default:
+			port.TargetPort = p.TargetPort.IntVal
+		}
+		ports = append(ports, port)
+	}
+
+	// Type defaults to ClusterIP when omitted from a manifest
+	svcType := string(svc.Spec.Type)
+	if svcType == "" {
+		svcType = string(corev1.ServiceTypeClusterIP)
+	}
+
+	var loadBalancer []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			loadBalancer = append(loadBalancer, ingress.IP)
+		} else if ingress.Hostname != "" {
+			loadBalancer = append(loadBalancer, ingress.Hostname)
+		}
+	}
+
+	return model.Service{
+		Name:         svc.Name,
+		Type:         svcType,
+		Selector:     svc.Spec.Selector,
+		Ports:        ports,
+		ExternalName: svc.Spec.ExternalName,
+		Headless:     svc.Spec.ClusterIP == corev1.ClusterIPNone,
+		LoadBalancer: loadBalancer,
+	}
+}
+
+// ConvertPVC converts a Kubernetes PersistentVolumeClaim to a model.PVC.
+// The bound capacity is preferred; the requested size is used for claims
+// that have not been bound yet (e.g. manifests that were never applied).
+func ConvertPVC(pvc *corev1.PersistentVolumeClaim) model.PVC {
+	storageClass := ""
+	if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	}
+
+	requested := ""
+	if storage, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		requested = storage.String()
+	}
+	capacity := requested
+	if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		capacity = storage.String()
+	}
+
+	return model.PVC{
+		Name:         pvc.Name,
+		StorageClass: storageClass,
+		Capacity:     capacity,
+		Requested:    requested,
+		BoundPod:     "", // TODO: determine which pod uses this PVC
+	}
+}
+
+// selectorLabels returns the matchLabels of a selector, tolerating a nil
+// selector in hand-written manifests.
+func selectorLabels(selector *metav1.LabelSelector) map[string]string {
+	if selector == nil {
+		return nil
+	}
+	return selector.MatchLabels
+}
+
+// optionalSelector copies a selector into the model, keeping nil as nil.
+func optionalSelector(selector *metav1.LabelSelector) *model.LabelSelector {
+	if selector == nil {
+		return nil
+	}
+	result := convertSelector(selector)
+	return &result
+}
+
+// ConvertIngress converts a Kubernetes Ingress to a model.Ingress. The
+// class comes from spec.ingressClassName, falling back to the legacy
+// kubernetes.io/ingress.class annotation.
+func ConvertIngress(ing *networkingv1.Ingress) model.Ingress {
+	class := ing.Annotations[ingressClassAnnotation]
+	if ing.Spec.IngressClassName != nil {
+		class = *ing.Spec.IngressClassName
+	}
+
+	result := model.Ingress{
+		Name:  ing.Name,
+		Class: class,
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		result.Hosts = appendUnique(result.Hosts, rule.Host)
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+			result.Routes = append(result.Routes, model.IngressRoute{
+				Host:    rule.Host,
+				Path:    path.Path,
+				Backend: ingressBackend(path.Backend.Service),
+			})
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		for _, host := range tls.Hosts {
+			result.Hosts = appendUnique(result.Hosts, host)
+		}
+		result.TLSSecrets = appendUnique(result.TLSSecrets, tls.SecretName)
+	}
+
+	if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+		backend := ingressBackend(ing.Spec.DefaultBackend.Service)
+		result.DefaultBackend = &backend
+	}
+
+	return result
+}
+
+// ingressClassAnnotation is the pre-IngressClass way of selecting a
+// controller, still common in older charts.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+func ingressBackend(svc *networkingv1.IngressServiceBackend) model.IngressBackend {
+	port := svc.Port.Name
+	if svc.Port.Number != 0 {
+		port = strconv.Itoa(int(svc.Port.Number))
+	}
+	return model.IngressBackend{Service: svc.Name, Port: port}
+}
+
+// appendUnique appends s unless it is empty or already present.
+func appendUnique(items []string, s string) []string {
+	if s == "" || slices.Contains(items, s) {
+		return items
+	}
+	return append(items, s)
+}
+
+// ConvertPDB converts a PodDisruptionBudget to a model.PDB. The allowed
+// disruptions are only kept once the disruption controller has observed
+// the budget.
+func ConvertPDB(pdb *policyv1.PodDisruptionBudget) model.PDB {
+	result := model.PDB{
+		Name:     pdb.Name,
+		Selector: optionalSelector(pdb.Spec.Selector),
+	}
+	if pdb.Spec.MinAvailable != nil {
+		result.MinAvailable = pdb.Spec.MinAvailable.String()
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		result.MaxUnavailable = pdb.Spec.MaxUnavailable.String()
+	}
+	if pdb.Status.ObservedGeneration > 0 {
+		allowed := pdb.Status.DisruptionsAllowed
+		result.DisruptionsAllowed = &allowed
+	}
+	return result
+}
+
+// ConvertNetworkPolicy converts a Kubernetes NetworkPolicy to a
+// model.NetworkPolicy. Policy types default as on the API server: Ingress
+// always, plus Egress when the policy has egress rules.
+func ConvertNetworkPolicy(np *networkingv1.NetworkPolicy) model.NetworkPolicy {
+	result := model.NetworkPolicy{
+		Name:        np.Name,
+		PodSelector: convertSelector(&np.Spec.PodSelector),
+	}
+
+	for _, t := range np.Spec.PolicyTypes {
+		result.PolicyTypes = append(result.PolicyTypes, string(t))
+	}
+	if len(result.PolicyTypes) == 0 {
+		result.PolicyTypes = []string{model.PolicyIngress}
+		if len(np.Spec.Egress) > 0 {
+			result.PolicyTypes = append(result.PolicyTypes, model.PolicyEgress)
+		}
+	}
+
+	for _, rule := range np.Spec.Ingress {
+		result.Ingress = append(result.Ingress, policyRule(rule.From, rule.Ports))
+	}
+	for _, rule := range np.Spec.Egress {
+		result.Egress = append(result.Egress, policyRule(rule.To, rule.Ports))
+	}
+
+	return result
+}
+
+func policyRule(peers []networkingv1.NetworkPolicyPeer, ports []networkingv1.NetworkPolicyPort) model.PolicyRule {
+	var rule model.PolicyRule
+	for _, p := range peers {
+		peer := model.PolicyPeer{}
+		if p.PodSelector != nil {
+			selector := convertSelector(p.PodSelector)
+			peer.PodSelector = &selector
+		}
+		if p.NamespaceSelector != nil {
+			selector := convertSelector(p.NamespaceSelector)
+			peer.NamespaceSelector = &selector
+		}
+		if p.IPBlock != nil {
+			peer.IPBlock = &model.IPBlock{CIDR: p.IPBlock.CIDR, Except: p.IPBlock.Except}
+		}
+		rule.Peers = append(rule.Peers, peer)
+	}
+
+	for _, p := range ports {
+		// Protocol defaults to TCP when omitted
+		port := model.PolicyPort{Protocol: string(corev1.ProtocolTCP)}
+		if p.Protocol != nil {
+			port.Protocol = string(*p.Protocol)
+		}
+		if p.Port != nil {
+			port.Port = p.Port.String()
+		}
+		if p.EndPort != nil {
+			port.EndPort = *p.EndPort
+		}
+		rule.Ports = append(rule.Ports, port)
+	}
+
+	return rule
+}
+
+// convertSelector copies a label selector into the model.
+func convertSelector(selector *metav1.LabelSelector) model.LabelSelector {
+	result := model.LabelSelector{MatchLabels: selector.MatchLabels}
+	for _, r := range selector.MatchExpressions {
+		result.MatchExpressions = append(result.MatchExpressions, model.LabelRequirement{
+			Key:      r.Key,
+			Operator: string(r.Operator),
+			Values:   r.Values,
+		})
+	}
+	return result
+}
+
+// NamespaceLabels returns a namespace's labels, including the name label
+// the API server adds, which manifests and unlisted namespaces lack.
+func NamespaceLabels(name string, labels map[string]string) map[string]string {
+	result := map[string]string{model.NamespaceNameLabel: name}
+	maps.Copy(result, labels)
+	return result
+}
@@ -0,0 +1,124 @@
+package kube
+
+import (
+	"context"
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AttachEndpoints sets the endpoints of every service from its
+// EndpointSlices, attributing each endpoint to the workload that owns its
+// pod. Endpoints of pods without a workload stay pods, and endpoints
+// without a pod, as on services without a selector, become addresses.
+// Endpoints listed in several slices (one per IP family) count once.
+// ExternalName services have no endpoints and are left alone.
+func AttachEndpoints(ns *model.Namespace, endpointSlices []*discoveryv1.EndpointSlice, replicaSets []*appsv1.ReplicaSet, pods []*corev1.Pod) {
+	owners := podWorkloads(ns, replicaSets, pods)
+
+	for i := range ns.Services {
+		svc := &ns.Services[i]
+		if svc.Type == string(corev1.ServiceTypeExternalName) {
+			continue
+		}
+
+		eps := &model.Endpoints{}
+		seen := make(map[string]bool)
+		for _, slice := range endpointSlices {
+			if slice.Labels[discoveryv1.LabelServiceName] != svc.Name {
+				continue
+			}
+			for _, ep := range slice.Endpoints {
+				key, target := endpointTarget(ep, owners)
+				if key == "" || seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+				j := slices.IndexFunc(eps.Targets, func(t model.EndpointTarget) bool { return t.Kind == target.Kind && t.Name == target.Name })
+				if j < 0 {
+					eps.Targets = append(eps.Targets, model.EndpointTarget{Kind: target.Kind, Name: target.Name})
+					j = len(eps.Targets) - 1
+				}
+				eps.Total++
+				eps.Targets[j].Total++
+				if ready {
+					eps.Ready++
+					eps.Targets[j].Ready++
+				}
+			}
+		}
+		svc.Endpoints = eps
+	}
+}
+
+// endpointTarget returns a key identifying an endpoint and the resource it
+// is attributed to. Endpoints with neither a target nor an address have an
+// empty key.
+func endpointTarget(ep discoveryv1.Endpoint, owners map[string]model.Ref) (string, model.Ref) {
+	if ref := ep.TargetRef; ref != nil {
+		if ref.Kind == model.KindPod {
+			if owner, ok := owners[ref.Name]; ok {
+				return "Pod/" + ref.Name, owner
+			}
+		}
+		return ref.Kind + "/" + ref.Name, model.Ref{Kind: ref.Kind, Name: ref.Name}
+	}
+	if len(ep.Addresses) == 0 {
+		return "", model.Ref{}
+	}
+	return model.KindAddress + "/" + ep.Addresses[0], model.Ref{Kind: model.KindAddress, Name: ep.Addresses[0]}
+}
+
+// podWorkloads maps each pod to the workload in the namespace that owns it,
+// through its ReplicaSet for Deployments. Pods without one are left out.
+func podWorkloads(ns *model.Namespace, replicaSets []*appsv1.ReplicaSet, pods []*corev1.Pod) map[string]model.Ref {
+	rsOwners := make(map[string]string)
+	for _, rs := range replicaSets {
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == model.KindDeployment {
+			rsOwners[rs.Name] = owner.Name
+		}
+	}
+
+	owners := make(map[string]model.Ref)
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			continue
+		}
+		kind, name := owner.Kind, owner.Name
+		if kind == model.KindReplicaSet {
+			deployment, ok := rsOwners[name]
+			if !ok {
+				continue
+			}
+			kind, name = model.KindDeployment, deployment
+		}
+		if ns.Workload(kind, name) != nil {
+			owners[pod.Name] = model.Ref{Kind: kind, Namespace: ns.Name, Name: name}
+		}
+	}
+	return owners
+}
+
+func (c *Client) fetchEndpoints(ctx context.Context, nsName string, ns *model.Namespace) error {
+	endpointSlices, err := c.lister.endpointSlices(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	replicaSets, err := c.lister.replicaSets(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	pods, err := c.lister.pods(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	AttachEndpoints(ns, endpointSlices, replicaSets, pods)
+	return nil
+}
@@ -6,15 +6,25 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/charmbracelet/log"
 	"github.com/vieitesss/k8s-d2/pkg/model"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type FetchOptions struct {
-	Namespace      string
-	AllNamespaces  bool
-	IncludeStorage bool
+	Namespace              string
+	AllNamespaces          bool
+	Namespaces             []string // Fetch only these namespaces, system ones included
+	IncludeStorage         bool
+	IncludeNetworkPolicies bool
+	IncludePDBs            bool
+	IncludeConfigRefs      bool // Workload references to ConfigMaps and Secrets
+	IncludeEndpoints       bool // Service wiring from EndpointSlices
+	IncludePods            bool // ReplicaSets and Pods of every workload
+	IncludeNodes           bool
 }
 
 // namespaceFetcher is a function that fetches a specific resource type into a namespace.
@@ -28,55 +38,97 @@ func (c *Client) FetchTopology(ctx context.Context, opts FetchOptions) (*model.C
 		return nil, err
 	}
 
-	for _, nsName := range namespaces {
-		ns, err := c.fetchNamespace(ctx, nsName, opts)
+	crds := c.customResources()
+	for _, item := range namespaces {
+		ns, err := c.fetchNamespace(ctx, item, opts, crds)
 		if err != nil {
 			return nil, err
 		}
 		cluster.Namespaces = append(cluster.Namespaces, *ns)
 	}
 
+	if opts.IncludeNodes {
+		if err := c.fetchNodes(ctx, cluster); err != nil {
+			return nil, err
+		}
+	}
+
+	ResolveReferenceGrants(cluster)
 	return cluster, nil
 }
 
-func (c *Client) getNamespaces(ctx context.Context, opts FetchOptions) ([]string, error) {
+// getNamespaces returns the namespaces to fetch. A namespace selected with
+// opts.Namespace is not read from the API, so it carries no labels.
+func (c *Client) getNamespaces(ctx context.Context, opts FetchOptions) ([]*corev1.Namespace, error) {
 	if opts.Namespace != "" {
-		return []string{opts.Namespace}, nil
+		return []*corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: opts.Namespace}}}, nil
 	}
 
-	list, err := c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	items, err := c.lister.namespaces(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.filterNamespaceNames(list.Items, opts.AllNamespaces), nil
+	return c.filterNamespaces(items, opts), nil
 }
 
-func (c *Client) filterNamespaceNames(items []corev1.Namespace, includeSystemNamespaces bool) []string {
-	var names []string
+func (c *Client) filterNamespaces(items []*corev1.Namespace, opts FetchOptions) []*corev1.Namespace {
+	var namespaces []*corev1.Namespace
 	for _, ns := range items {
-		if !includeSystemNamespaces && isSystemNamespace(ns.Name) {
+		if len(opts.Namespaces) > 0 {
+			if !slices.Contains(opts.Namespaces, ns.Name) {
+				continue
+			}
+		} else if !opts.AllNamespaces && isSystemNamespace(ns.Name) {
 			continue
 		}
-		names = append(names, ns.Name)
+		namespaces = append(namespaces, ns)
 	}
-	return names
+	return namespaces
 }
 
-func (c *Client) fetchNamespace(ctx context.Context, nsName string, opts FetchOptions) (*model.Namespace, error) {
+func (c *Client) fetchNamespace(ctx context.Context, item *corev1.Namespace, opts FetchOptions, crds map[string]schema.GroupVersionResource) (*model.Namespace, error) {
+	nsName := item.Name
 	ns := &model.Namespace{Name: nsName}
 
 	fetchers := []namespaceFetcher{
 		c.fetchDeployments,
 		c.fetchStatefulSets,
 		c.fetchDaemonSets,
+		c.fetchCronJobs,
+		c.fetchJobs,
 		c.fetchServices,
 		c.fetchConfigMapsAndSecrets,
+		c.fetchIngresses,
+		// Autoscalers are attached to the workloads fetched above
+		func(ctx context.Context, nsName string, ns *model.Namespace) error {
+			return c.fetchAutoscalers(ctx, crds, nsName, ns)
+		},
 	}
 
 	if opts.IncludeStorage {
 		fetchers = append(fetchers, c.fetchPVCs)
 	}
+	if len(crds) > 0 {
+		fetchers = append(fetchers, func(ctx context.Context, nsName string, ns *model.Namespace) error {
+			return c.fetchGatewayAPI(ctx, crds, nsName, ns)
+		})
+	}
+
+	if opts.IncludeNetworkPolicies {
+		ns.Labels = NamespaceLabels(nsName, item.Labels)
+		fetchers = append(fetchers, c.fetchNetworkPolicies)
+	}
+	if opts.IncludePDBs {
+		fetchers = append(fetchers, c.fetchPDBs)
+	}
+	// Pods are attached to the workloads fetched above
+	if opts.IncludePods {
+		fetchers = append(fetchers, c.fetchPods)
+	}
+	if opts.IncludeEndpoints {
+		fetchers = append(fetchers, c.fetchEndpoints)
+	}
 
 	for _, fetch := range fetchers {
 		if err := fetch(ctx, nsName, ns); err != nil {
@@ -84,161 +136,185 @@ func (c *Client) fetchNamespace(ctx context.Context, nsName string, opts FetchOp
 		}
 	}
 
+	if opts.IncludeNetworkPolicies {
+		ns.ResolveNetworkAccess()
+	}
+	if !opts.IncludeConfigRefs {
+		ns.ClearConfigRefs()
+	}
 	return ns, nil
 }
 
 func (c *Client) fetchDeployments(ctx context.Context, nsName string, ns *model.Namespace) error {
-	deps, err := c.clientset.AppsV1().Deployments(nsName).List(ctx, metav1.ListOptions{})
+	deps, err := c.lister.deployments(ctx, nsName)
 	if err != nil {
 		return err
 	}
-	for _, d := range deps.Items {
-		volumeMounts := ExtractVolumeMounts(
-			d.Spec.Template.Spec.Containers,
-			d.Spec.Template.Spec.Volumes,
-		)
-		ns.Deployments = append(ns.Deployments, model.Workload{
-			Name:         d.Name,
-			Kind:         "Deployment",
-			Replicas:     *d.Spec.Replicas,
-			Labels:       d.Spec.Selector.MatchLabels,
-			VolumeMounts: volumeMounts,
-		})
+	for _, d := range deps {
+		ns.Deployments = append(ns.Deployments, ConvertDeployment(d))
 	}
 	return nil
 }
 
 func (c *Client) fetchStatefulSets(ctx context.Context, nsName string, ns *model.Namespace) error {
-	ssets, err := c.clientset.AppsV1().StatefulSets(nsName).List(ctx, metav1.ListOptions{})
+	ssets, err := c.lister.statefulSets(ctx, nsName)
 	if err != nil {
 		return err
 	}
-	for _, ss := range ssets.Items {
-		// Default to 1 replica if not specified (Kubernetes StatefulSet default)
-		replicas := int32(1)
-		if ss.Spec.Replicas != nil {
-			replicas = *ss.Spec.Replicas
-		}
-
-		volumeMounts := ExtractAllStatefulSetVolumeMounts(
-			ss.Spec.Template.Spec.Containers,
-			ss.Spec.Template.Spec.Volumes,
-			ss.Spec.VolumeClaimTemplates,
-			ss.Name,
-			replicas,
-		)
-		ns.StatefulSets = append(ns.StatefulSets, model.Workload{
-			Name:         ss.Name,
-			Kind:         "StatefulSet",
-			Replicas:     replicas,
-			Labels:       ss.Spec.Selector.MatchLabels,
-			VolumeMounts: volumeMounts,
-		})
+	for _, ss := range ssets {
+		ns.StatefulSets = append(ns.StatefulSets, ConvertStatefulSet(ss))
 	}
 	return nil
 }
 
 func (c *Client) fetchDaemonSets(ctx context.Context, nsName string, ns *model.Namespace) error {
-	dsets, err := c.clientset.AppsV1().DaemonSets(nsName).List(ctx, metav1.ListOptions{})
+	dsets, err := c.lister.daemonSets(ctx, nsName)
 	if err != nil {
 		return err
 	}
-	for _, ds := range dsets.Items {
-		volumeMounts := ExtractVolumeMounts(
-			ds.Spec.Template.Spec.Containers,
-			ds.Spec.Template.Spec.Volumes,
-		)
-		ns.DaemonSets = append(ns.DaemonSets, model.Workload{
-			Name:         ds.Name,
-			Kind:         "DaemonSet",
-			Replicas:     ds.Status.DesiredNumberScheduled,
-			Labels:       ds.Spec.Selector.MatchLabels,
-			VolumeMounts: volumeMounts,
-		})
+	for _, ds := range dsets {
+		ns.DaemonSets = append(ns.DaemonSets, ConvertDaemonSet(ds))
+	}
+	return nil
+}
+
+func (c *Client) fetchCronJobs(ctx context.Context, nsName string, ns *model.Namespace) error {
+	cronJobs, err := c.lister.cronJobs(ctx, nsName)
+	if err != nil {
+		return skipUnavailable(err, resourceCronJobs, nsName)
+	}
+	for _, cj := range cronJobs {
+		ns.CronJobs = append(ns.CronJobs, ConvertCronJob(cj))
+	}
+	return nil
+}
+
+func (c *Client) fetchJobs(ctx context.Context, nsName string, ns *model.Namespace) error {
+	jobs, err := c.lister.jobs(ctx, nsName)
+	if err != nil {
+		return skipUnavailable(err, resourceJobs, nsName)
+	}
+	for _, job := range jobs {
+		ns.Jobs = append(ns.Jobs, ConvertJob(job))
 	}
 	return nil
 }
 
 func (c *Client) fetchServices(ctx context.Context, nsName string, ns *model.Namespace) error {
-	svcs, err := c.clientset.CoreV1().Services(nsName).List(ctx, metav1.ListOptions{})
+	svcs, err := c.lister.services(ctx, nsName)
 	if err != nil {
 		return err
 	}
-	for _, svc := range svcs.Items {
-		ports := []model.Port{}
-		for _, p := range svc.Spec.Ports {
-			ports = append(ports, model.Port{
-				Name:       p.Name,
-				Port:       p.Port,
-				TargetPort: p.TargetPort.IntVal,
-			})
-		}
-		ns.Services = append(ns.Services, model.Service{
-			Name:     svc.Name,
-			Type:     string(svc.Spec.Type),
-			Selector: svc.Spec.Selector,
-			Ports:    ports,
-		})
+	for _, svc := range svcs {
+		ns.Services = append(ns.Services, ConvertService(svc))
 	}
 	return nil
 }
 
+// fetchConfigMapsAndSecrets counts the user ConfigMaps and Secrets, and
+// marks the workload references to objects that do not exist. System
+// objects are not counted but can still be referenced.
 func (c *Client) fetchConfigMapsAndSecrets(ctx context.Context, nsName string, ns *model.Namespace) error {
-	cms, err := c.clientset.CoreV1().ConfigMaps(nsName).List(ctx, metav1.ListOptions{})
+	cms, err := c.lister.configMaps(ctx, nsName)
 	if err != nil {
 		return err
 	}
 
 	// Filter out system-managed ConfigMaps
 	userConfigMaps := 0
-	for _, cm := range cms.Items {
-		if !isSystemConfigMap(cm.Name) {
+	cmNames := make([]string, 0, len(cms))
+	for _, cm := range cms {
+		cmNames = append(cmNames, cm.Name)
+		if !IsSystemConfigMap(cm.Name) {
 			userConfigMaps++
 		}
 	}
 	ns.ConfigMaps = userConfigMaps
 
-	secrets, err := c.clientset.CoreV1().Secrets(nsName).List(ctx, metav1.ListOptions{})
+	secrets, err := c.lister.secrets(ctx, nsName)
 	if err != nil {
 		return err
 	}
 
 	// Filter out system-managed Secrets (service account tokens)
 	userSecrets := 0
-	for _, secret := range secrets.Items {
-		if !isSystemSecret(secret.Name, secret.Type) {
+	secretNames := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		secretNames = append(secretNames, secret.Name)
+		if !IsSystemSecret(secret.Name, secret.Type) {
 			userSecrets++
 		}
 	}
 	ns.Secrets = userSecrets
 
+	ns.ResolveConfigRefs(cmNames, secretNames)
 	return nil
 }
 
 func (c *Client) fetchPVCs(ctx context.Context, nsName string, ns *model.Namespace) error {
-	pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(nsName).List(ctx, metav1.ListOptions{})
+	pvcs, err := c.lister.pvcs(ctx, nsName)
 	if err != nil {
 		return err
 	}
-	for _, pvc := range pvcs.Items {
-		storageClass := ""
-		if pvc.Spec.StorageClassName != nil {
-			storageClass = *pvc.Spec.StorageClassName
-		}
-		capacity := ""
-		if storage, ok := pvc.Status.Capacity["storage"]; ok {
-			capacity = storage.String()
-		}
-		ns.PVCs = append(ns.PVCs, model.PVC{
-			Name:         pvc.Name,
-			StorageClass: storageClass,
-			Capacity:     capacity,
-			BoundPod:     "", // TODO: determine which pod uses this PVC
-		})
+	for _, pvc := range pvcs {
+		ns.PVCs = append(ns.PVCs, ConvertPVC(pvc))
+	}
+	return nil
+}
+
+func (c *Client) fetchIngresses(ctx context.Context, nsName string, ns *model.Namespace) error {
+	ings, err := c.lister.ingresses(ctx, nsName)
+	if err != nil {
+		return skipUnavailable(err, resourceIngresses, nsName)
+	}
+	for _, ing := range ings {
+		ns.Ingresses = append(ns.Ingresses, ConvertIngress(ing))
+	}
+	return nil
+}
+
+func (c *Client) fetchNetworkPolicies(ctx context.Context, nsName string, ns *model.Namespace) error {
+	policies, err := c.lister.networkPolicies(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	for _, np := range policies {
+		ns.NetworkPolicies = append(ns.NetworkPolicies, ConvertNetworkPolicy(np))
 	}
 	return nil
 }
 
+func (c *Client) fetchPDBs(ctx context.Context, nsName string, ns *model.Namespace) error {
+	pdbs, err := c.lister.pdbs(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	for _, pdb := range pdbs {
+		ns.PDBs = append(ns.PDBs, ConvertPDB(pdb))
+	}
+	return nil
+}
+
+// Resource types every fetch lists although the client may not be allowed
+// to: read-only ServiceAccounts are often limited to the core workloads.
+const (
+	resourceCronJobs   = "cronjobs"
+	resourceJobs       = "jobs"
+	resourceIngresses  = "ingresses"
+	resourceAutoscaler = "horizontalpodautoscalers"
+)
+
+// skipUnavailable treats a list the client is forbidden to make, or that
+// the API server does not serve, as empty so the rest of the topology is
+// still built. Other errors are returned as is.
+func skipUnavailable(err error, resource, nsName string) error {
+	if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+		log.Debug("Skipping resources the client cannot list", "resource", resource, "namespace", nsName, "err", err)
+		return nil
+	}
+	return err
+}
+
 func isSystemNamespace(name string) bool {
 	systemPrefixes := []string{"kube-", "openshift-", "istio-"}
 	systemNames := []string{"default", "kube-system", "kube-public", "kube-node-lease"}
@@ -255,7 +331,9 @@ func isSystemNamespace(name string) bool {
 	return false
 }
 
-func isSystemConfigMap(name string) bool {
+// IsSystemConfigMap reports whether a ConfigMap is injected by Kubernetes or a
+// service mesh rather than created by the user.
+func IsSystemConfigMap(name string) bool {
 	// Known system-managed ConfigMaps
 	systemConfigMaps := []string{
 		"kube-root-ca.crt",   // Kubernetes cluster CA certificate (injected in all namespaces)
@@ -278,7 +356,9 @@ func isSystemConfigMap(name string) bool {
 	return false
 }
 
-func isSystemSecret(name string, secretType corev1.SecretType) bool {
+// IsSystemSecret reports whether a Secret is managed by the system (service
+// account tokens, Helm release records) rather than created by the user.
+func IsSystemSecret(name string, secretType corev1.SecretType) bool {
 	// Filter out service account token secrets
 	if secretType == corev1.SecretTypeServiceAccountToken {
 		return true
@@ -0,0 +1,290 @@
+package kube
+
+import (
+	"context"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// GatewayGroup is the API group of the Gateway API CRDs.
+const GatewayGroup = "gateway.networking.k8s.io"
+
+// KindReferenceGrant is the Gateway API kind that allows cross-namespace
+// references. It is not drawn, so it is not one of the model kinds.
+const KindReferenceGrant = "ReferenceGrant"
+
+// gatewayKind is a Gateway API kind with the versions it may be served at,
+// most preferred first. Route kinds are listed alphabetically, the order the
+// manifest loader sorts routes in.
+type gatewayKind struct {
+	kind     string
+	resource string
+	versions []string
+}
+
+var gatewayKinds = []gatewayKind{
+	{kind: model.KindGateway, resource: "gateways", versions: []string{"v1", "v1beta1"}},
+	{kind: model.KindGRPCRoute, resource: "grpcroutes", versions: []string{"v1", "v1alpha2"}},
+	{kind: model.KindHTTPRoute, resource: "httproutes", versions: []string{"v1", "v1beta1"}},
+	{kind: model.KindTCPRoute, resource: "tcproutes", versions: []string{"v1alpha2"}},
+	{kind: KindReferenceGrant, resource: "referencegrants", versions: []string{"v1", "v1beta1"}},
+}
+
+// discoverGatewayAPI returns the resource served for each Gateway API kind.
+// Kinds whose CRDs are not installed are left out, and discovery errors are
+// treated as "not installed", so clusters without Gateway API (or without
+// permission to discover it) produce an empty map instead of an error.
+func discoverGatewayAPI(disc discovery.DiscoveryInterface) map[string]schema.GroupVersionResource {
+	served := make(map[string]map[string]bool)
+	for _, version := range []string{"v1", "v1beta1", "v1alpha2"} {
+		list, err := disc.ServerResourcesForGroupVersion(GatewayGroup + "/" + version)
+		if err != nil {
+			continue
+		}
+		served[version] = make(map[string]bool)
+		for _, r := range list.APIResources {
+			served[version][r.Name] = true
+		}
+	}
+
+	gvrs := make(map[string]schema.GroupVersionResource)
+	for _, gk := range gatewayKinds {
+		for _, version := range gk.versions {
+			if served[version][gk.resource] {
+				gvrs[gk.kind] = schema.GroupVersionResource{Group: GatewayGroup, Version: version, Resource: gk.resource}
+				break
+			}
+		}
+	}
+	return gvrs
+}
+
+func (c *Client) fetchGatewayAPI(ctx context.Context, gvrs map[string]schema.GroupVersionResource, nsName string, ns *model.Namespace) error {
+	for _, gk := range gatewayKinds {
+		gvr, ok := gvrs[gk.kind]
+		if !ok {
+			continue
+		}
+		objects, err := c.lister.customObjects(ctx, gvr, nsName)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if err := addGatewayObject(ns, gk.kind, obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addGatewayObject converts an unstructured Gateway API object into the
+// namespace. Objects served at older versions decode into the same structs,
+// since the fields used here did not change between versions.
+func addGatewayObject(ns *model.Namespace, kind string, obj *unstructured.Unstructured) error {
+	convert := runtime.DefaultUnstructuredConverter.FromUnstructured
+
+	switch kind {
+	case model.KindGateway:
+		var gw gatewayv1.Gateway
+		if err := convert(obj.Object, &gw); err != nil {
+			return err
+		}
+		ns.Gateways = append(ns.Gateways, ConvertGateway(&gw))
+	case model.KindHTTPRoute:
+		var route gatewayv1.HTTPRoute
+		if err := convert(obj.Object, &route); err != nil {
+			return err
+		}
+		ns.Routes = append(ns.Routes, ConvertHTTPRoute(&route))
+	case model.KindGRPCRoute:
+		var route gatewayv1alpha2.GRPCRoute
+		if err := convert(obj.Object, &route); err != nil {
+			return err
+		}
+		ns.Routes = append(ns.Routes, ConvertGRPCRoute(&route))
+	case model.KindTCPRoute:
+		var route gatewayv1alpha2.TCPRoute
+		if err := convert(obj.Object, &route); err != nil {
+			return err
+		}
+		ns.Routes = append(ns.Routes, ConvertTCPRoute(&route))
+	case KindReferenceGrant:
+		var grant gatewayv1beta1.ReferenceGrant
+		if err := convert(obj.Object, &grant); err != nil {
+			return err
+		}
+		ns.ReferenceGrants = append(ns.ReferenceGrants, ConvertReferenceGrant(&grant))
+	}
+	return nil
+}
+
+// ConvertGateway converts a Gateway API Gateway to a model.Gateway.
+func ConvertGateway(gw *gatewayv1.Gateway) model.Gateway {
+	result := model.Gateway{
+		Name:  gw.Name,
+		Class: string(gw.Spec.GatewayClassName),
+	}
+	for _, l := range gw.Spec.Listeners {
+		listener := model.Listener{
+			Name:     string(l.Name),
+			Protocol: string(l.Protocol),
+			Port:     int32(l.Port),
+		}
+		if l.Hostname != nil {
+			listener.Hostname = string(*l.Hostname)
+		}
+		result.Listeners = append(result.Listeners, listener)
+	}
+	return result
+}
+
+// ConvertHTTPRoute converts an HTTPRoute to a model.Route. Parent and
+// backend references without a namespace resolve to the route's namespace.
+func ConvertHTTPRoute(route *gatewayv1.HTTPRoute) model.Route {
+	result := newRoute(model.KindHTTPRoute, &route.ObjectMeta, route.Spec.ParentRefs, route.Spec.Hostnames)
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			result.Backends = appendBackend(result.Backends, route.Namespace, &ref.BackendRef)
+		}
+	}
+	return result
+}
+
+// ConvertGRPCRoute converts a GRPCRoute to a model.Route.
+func ConvertGRPCRoute(route *gatewayv1alpha2.GRPCRoute) model.Route {
+	result := newRoute(model.KindGRPCRoute, &route.ObjectMeta, route.Spec.ParentRefs, route.Spec.Hostnames)
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			result.Backends = appendBackend(result.Backends, route.Namespace, &ref.BackendRef)
+		}
+	}
+	return result
+}
+
+// ConvertTCPRoute converts a TCPRoute to a model.Route.
+func ConvertTCPRoute(route *gatewayv1alpha2.TCPRoute) model.Route {
+	result := newRoute(model.KindTCPRoute, &route.ObjectMeta, route.Spec.ParentRefs, nil)
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			result.Backends = appendBackend(result.Backends, route.Namespace, &ref)
+		}
+	}
+	return result
+}
+
+// ConvertReferenceGrant converts a ReferenceGrant to a model.ReferenceGrant.
+func ConvertReferenceGrant(grant *gatewayv1beta1.ReferenceGrant) model.ReferenceGrant {
+	result := model.ReferenceGrant{Name: grant.Name}
+	for _, from := range grant.Spec.From {
+		result.From = append(result.From, model.GrantFrom{Kind: string(from.Kind), Namespace: string(from.Namespace)})
+	}
+	for _, to := range grant.Spec.To {
+		target := model.GrantTarget{Kind: string(to.Kind)}
+		if to.Name != nil {
+			target.Name = string(*to.Name)
+		}
+		result.To = append(result.To, target)
+	}
+	return result
+}
+
+func newRoute(kind string, meta *metav1.ObjectMeta, parents []gatewayv1.ParentReference, hostnames []gatewayv1.Hostname) model.Route {
+	route := model.Route{Name: meta.Name, Kind: kind}
+	namespace := meta.Namespace
+
+	for _, h := range hostnames {
+		route.Hostnames = append(route.Hostnames, string(h))
+	}
+	for _, p := range parents {
+		// Only Gateways are drawn as parents
+		if p.Kind != nil && string(*p.Kind) != model.KindGateway {
+			continue
+		}
+		parent := model.ParentRef{Namespace: namespace, Name: string(p.Name)}
+		if p.Namespace != nil {
+			parent.Namespace = string(*p.Namespace)
+		}
+		if p.SectionName != nil {
+			parent.SectionName = string(*p.SectionName)
+		}
+		route.ParentRefs = append(route.ParentRefs, parent)
+	}
+	return route
+}
+
+// appendBackend adds a backendRef pointing at a Service; other backend
+// kinds are not part of the topology. Weights default to 1.
+func appendBackend(backends []model.RouteBackend, routeNamespace string, ref *gatewayv1.BackendRef) []model.RouteBackend {
+	if ref.Kind != nil && string(*ref.Kind) != model.KindService {
+		return backends
+	}
+
+	backend := model.RouteBackend{Namespace: routeNamespace, Service: string(ref.Name), Weight: 1}
+	if ref.Namespace != nil {
+		backend.Namespace = string(*ref.Namespace)
+	}
+	if ref.Port != nil {
+		backend.Port = int32(*ref.Port)
+	}
+	if ref.Weight != nil {
+		backend.Weight = *ref.Weight
+	}
+	return append(backends, backend)
+}
+
+// ResolveReferenceGrants marks cross-namespace route backends that no
+// ReferenceGrant in the target namespace allows. Backends in namespaces
+// outside the cluster model are left alone, since their grants are unknown.
+func ResolveReferenceGrants(cluster *model.Cluster) {
+	grants := make(map[string][]model.ReferenceGrant)
+	for _, ns := range cluster.Namespaces {
+		grants[ns.Name] = ns.ReferenceGrants
+	}
+
+	for i := range cluster.Namespaces {
+		ns := &cluster.Namespaces[i]
+		for j := range ns.Routes {
+			route := &ns.Routes[j]
+			for k := range route.Backends {
+				backend := &route.Backends[k]
+				targetGrants, known := grants[backend.Namespace]
+				if backend.Namespace == ns.Name || !known {
+					continue
+				}
+				backend.Denied = !grantAllows(targetGrants, route.Kind, ns.Name, backend.Service)
+			}
+		}
+	}
+}
+
+func grantAllows(grants []model.ReferenceGrant, fromKind, fromNamespace, service string) bool {
+	for _, g := range grants {
+		if !grantFromMatches(g.From, fromKind, fromNamespace) {
+			continue
+		}
+		for _, to := range g.To {
+			if to.Kind == model.KindService && (to.Name == "" || to.Name == service) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func grantFromMatches(from []model.GrantFrom, kind, namespace string) bool {
+	for _, f := range from {
+		if f.Kind == kind && f.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
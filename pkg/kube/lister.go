@@ -0,0 +1,456 @@
+package kube
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	autoscalinglisters "k8s.io/client-go/listers/autoscaling/v2"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceLister lists the resources a topology is built from. Results are
+// sorted by name so diagrams are stable regardless of the backing store.
+type resourceLister interface {
+	namespaces(ctx context.Context) ([]*corev1.Namespace, error)
+	nodes(ctx context.Context) ([]*corev1.Node, error)
+	deployments(ctx context.Context, ns string) ([]*appsv1.Deployment, error)
+	statefulSets(ctx context.Context, ns string) ([]*appsv1.StatefulSet, error)
+	daemonSets(ctx context.Context, ns string) ([]*appsv1.DaemonSet, error)
+	cronJobs(ctx context.Context, ns string) ([]*batchv1.CronJob, error)
+	jobs(ctx context.Context, ns string) ([]*batchv1.Job, error)
+	replicaSets(ctx context.Context, ns string) ([]*appsv1.ReplicaSet, error)
+	pods(ctx context.Context, ns string) ([]*corev1.Pod, error)
+	services(ctx context.Context, ns string) ([]*corev1.Service, error)
+	endpointSlices(ctx context.Context, ns string) ([]*discoveryv1.EndpointSlice, error)
+	configMaps(ctx context.Context, ns string) ([]*corev1.ConfigMap, error)
+	secrets(ctx context.Context, ns string) ([]*corev1.Secret, error)
+	pvcs(ctx context.Context, ns string) ([]*corev1.PersistentVolumeClaim, error)
+	ingresses(ctx context.Context, ns string) ([]*networkingv1.Ingress, error)
+	networkPolicies(ctx context.Context, ns string) ([]*networkingv1.NetworkPolicy, error)
+	pdbs(ctx context.Context, ns string) ([]*policyv1.PodDisruptionBudget, error)
+	hpas(ctx context.Context, ns string) ([]*autoscalingv2.HorizontalPodAutoscaler, error)
+	customObjects(ctx context.Context, gvr schema.GroupVersionResource, ns string) ([]*unstructured.Unstructured, error)
+}
+
+// apiLister lists resources straight from the API server. CRD-backed
+// resources are listed through the dynamic client, when there is one.
+type apiLister struct {
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+}
+
+func (l apiLister) namespaces(ctx context.Context) ([]*corev1.Namespace, error) {
+	list, err := l.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) nodes(ctx context.Context) ([]*corev1.Node, error) {
+	list, err := l.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) deployments(ctx context.Context, ns string) ([]*appsv1.Deployment, error) {
+	list, err := l.clientset.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) statefulSets(ctx context.Context, ns string) ([]*appsv1.StatefulSet, error) {
+	list, err := l.clientset.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) daemonSets(ctx context.Context, ns string) ([]*appsv1.DaemonSet, error) {
+	list, err := l.clientset.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) cronJobs(ctx context.Context, ns string) ([]*batchv1.CronJob, error) {
+	list, err := l.clientset.BatchV1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) jobs(ctx context.Context, ns string) ([]*batchv1.Job, error) {
+	list, err := l.clientset.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) replicaSets(ctx context.Context, ns string) ([]*appsv1.ReplicaSet, error) {
+	list, err := l.clientset.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) pods(ctx context.Context, ns string) ([]*corev1.Pod, error) {
+	list, err := l.clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) services(ctx context.Context, ns string) ([]*corev1.Service, error) {
+	list, err := l.clientset.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) endpointSlices(ctx context.Context, ns string) ([]*discoveryv1.EndpointSlice, error) {
+	list, err := l.clientset.DiscoveryV1().EndpointSlices(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) configMaps(ctx context.Context, ns string) ([]*corev1.ConfigMap, error) {
+	list, err := l.clientset.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) secrets(ctx context.Context, ns string) ([]*corev1.Secret, error) {
+	list, err := l.clientset.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) pvcs(ctx context.Context, ns string) ([]*corev1.PersistentVolumeClaim, error) {
+	list, err := l.clientset.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) ingresses(ctx context.Context, ns string) ([]*networkingv1.Ingress, error) {
+	list, err := l.clientset.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) networkPolicies(ctx context.Context, ns string) ([]*networkingv1.NetworkPolicy, error) {
+	list, err := l.clientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) pdbs(ctx context.Context, ns string) ([]*policyv1.PodDisruptionBudget, error) {
+	list, err := l.clientset.PolicyV1().PodDisruptionBudgets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) hpas(ctx context.Context, ns string) ([]*autoscalingv2.HorizontalPodAutoscaler, error) {
+	list, err := l.clientset.AutoscalingV2().HorizontalPodAutoscalers(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+func (l apiLister) customObjects(ctx context.Context, gvr schema.GroupVersionResource, ns string) ([]*unstructured.Unstructured, error) {
+	if l.dynamic == nil {
+		return nil, nil
+	}
+	list, err := l.dynamic.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pointers(list.Items), nil
+}
+
+// cacheLister lists resources from shared informer caches, so repeated
+// topology builds do not hit the API server.
+type cacheLister struct {
+	namespaceLister   corelisters.NamespaceLister
+	nodeLister        corelisters.NodeLister
+	deploymentLister  appslisters.DeploymentLister
+	statefulSetLister appslisters.StatefulSetLister
+	daemonSetLister   appslisters.DaemonSetLister
+	cronJobLister     batchlisters.CronJobLister
+	jobLister         batchlisters.JobLister
+	replicaSetLister  appslisters.ReplicaSetLister
+	podLister         corelisters.PodLister
+	serviceLister     corelisters.ServiceLister
+	sliceLister       discoverylisters.EndpointSliceLister
+	configMapLister   corelisters.ConfigMapLister
+	secretLister      corelisters.SecretLister
+	pvcLister         corelisters.PersistentVolumeClaimLister
+	ingressLister     networkinglisters.IngressLister
+	policyLister      networkinglisters.NetworkPolicyLister
+	pdbLister         policylisters.PodDisruptionBudgetLister
+	hpaLister         autoscalinglisters.HorizontalPodAutoscalerLister
+	customListers     map[schema.GroupVersionResource]cache.GenericLister
+}
+
+// newCacheLister registers an informer on the factory for every resource
+// type fetchNamespace lists with opts, and returns them alongside the
+// lister. Resource types in unavailable get no informer and list as empty.
+func newCacheLister(factory informers.SharedInformerFactory, opts FetchOptions, unavailable map[string]bool) (*cacheLister, []cache.SharedIndexInformer) {
+	apps := factory.Apps().V1()
+	autoscaling := factory.Autoscaling().V2()
+	batch := factory.Batch().V1()
+	core := factory.Core().V1()
+	discovery := factory.Discovery().V1()
+	networking := factory.Networking().V1()
+	policy := factory.Policy().V1()
+
+	l := &cacheLister{
+		deploymentLister:  apps.Deployments().Lister(),
+		statefulSetLister: apps.StatefulSets().Lister(),
+		daemonSetLister:   apps.DaemonSets().Lister(),
+		serviceLister:     core.Services().Lister(),
+		configMapLister:   core.ConfigMaps().Lister(),
+		secretLister:      core.Secrets().Lister(),
+	}
+	registered := []cache.SharedIndexInformer{
+		apps.Deployments().Informer(),
+		apps.StatefulSets().Informer(),
+		apps.DaemonSets().Informer(),
+		core.Services().Informer(),
+		core.ConfigMaps().Informer(),
+		core.Secrets().Informer(),
+	}
+
+	if !unavailable[resourceCronJobs] {
+		l.cronJobLister = batch.CronJobs().Lister()
+		registered = append(registered, batch.CronJobs().Informer())
+	}
+	if !unavailable[resourceJobs] {
+		l.jobLister = batch.Jobs().Lister()
+		registered = append(registered, batch.Jobs().Informer())
+	}
+	if !unavailable[resourceIngresses] {
+		l.ingressLister = networking.Ingresses().Lister()
+		registered = append(registered, networking.Ingresses().Informer())
+	}
+	if !unavailable[resourceAutoscaler] {
+		l.hpaLister = autoscaling.HorizontalPodAutoscalers().Lister()
+		registered = append(registered, autoscaling.HorizontalPodAutoscalers().Informer())
+	}
+
+	if opts.Namespace == "" {
+		l.namespaceLister = core.Namespaces().Lister()
+		registered = append(registered, core.Namespaces().Informer())
+	}
+	if opts.IncludeStorage {
+		l.pvcLister = core.PersistentVolumeClaims().Lister()
+		registered = append(registered, core.PersistentVolumeClaims().Informer())
+	}
+	if opts.IncludeNetworkPolicies {
+		l.policyLister = networking.NetworkPolicies().Lister()
+		registered = append(registered, networking.NetworkPolicies().Informer())
+	}
+	if opts.IncludePDBs {
+		l.pdbLister = policy.PodDisruptionBudgets().Lister()
+		registered = append(registered, policy.PodDisruptionBudgets().Informer())
+	}
+	if opts.IncludeEndpoints {
+		l.sliceLister = discovery.EndpointSlices().Lister()
+		registered = append(registered, discovery.EndpointSlices().Informer())
+	}
+	if opts.IncludePods || opts.IncludeEndpoints {
+		l.replicaSetLister = apps.ReplicaSets().Lister()
+		l.podLister = core.Pods().Lister()
+		registered = append(registered, apps.ReplicaSets().Informer(), core.Pods().Informer())
+	}
+	if opts.IncludeNodes {
+		l.nodeLister = core.Nodes().Lister()
+		registered = append(registered, core.Nodes().Informer())
+	}
+
+	return l, registered
+}
+
+// addCustomInformers registers a dynamic informer for each discovered
+// CRD-backed resource and returns the informers.
+func (l *cacheLister) addCustomInformers(factory dynamicinformer.DynamicSharedInformerFactory, gvrs map[string]schema.GroupVersionResource) []cache.SharedIndexInformer {
+	l.customListers = make(map[schema.GroupVersionResource]cache.GenericLister)
+
+	var registered []cache.SharedIndexInformer
+	for _, gvr := range gvrs {
+		informer := factory.ForResource(gvr)
+		l.customListers[gvr] = informer.Lister()
+		registered = append(registered, informer.Informer())
+	}
+	return registered
+}
+
+func (l *cacheLister) namespaces(context.Context) ([]*corev1.Namespace, error) {
+	return sortedByName(l.namespaceLister.List(labels.Everything()))
+}
+
+func (l *cacheLister) nodes(context.Context) ([]*corev1.Node, error) {
+	return sortedByName(l.nodeLister.List(labels.Everything()))
+}
+
+func (l *cacheLister) deployments(_ context.Context, ns string) ([]*appsv1.Deployment, error) {
+	return sortedByName(l.deploymentLister.Deployments(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) statefulSets(_ context.Context, ns string) ([]*appsv1.StatefulSet, error) {
+	return sortedByName(l.statefulSetLister.StatefulSets(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) daemonSets(_ context.Context, ns string) ([]*appsv1.DaemonSet, error) {
+	return sortedByName(l.daemonSetLister.DaemonSets(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) cronJobs(_ context.Context, ns string) ([]*batchv1.CronJob, error) {
+	if l.cronJobLister == nil {
+		return nil, nil
+	}
+	return sortedByName(l.cronJobLister.CronJobs(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) jobs(_ context.Context, ns string) ([]*batchv1.Job, error) {
+	if l.jobLister == nil {
+		return nil, nil
+	}
+	return sortedByName(l.jobLister.Jobs(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) replicaSets(_ context.Context, ns string) ([]*appsv1.ReplicaSet, error) {
+	return sortedByName(l.replicaSetLister.ReplicaSets(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) pods(_ context.Context, ns string) ([]*corev1.Pod, error) {
+	return sortedByName(l.podLister.Pods(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) services(_ context.Context, ns string) ([]*corev1.Service, error) {
+	return sortedByName(l.serviceLister.Services(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) configMaps(_ context.Context, ns string) ([]*corev1.ConfigMap, error) {
+	return sortedByName(l.configMapLister.ConfigMaps(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) secrets(_ context.Context, ns string) ([]*corev1.Secret, error) {
+	return sortedByName(l.secretLister.Secrets(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) pvcs(_ context.Context, ns string) ([]*corev1.PersistentVolumeClaim, error) {
+	return sortedByName(l.pvcLister.PersistentVolumeClaims(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) ingresses(_ context.Context, ns string) ([]*networkingv1.Ingress, error) {
+	if l.ingressLister == nil {
+		return nil, nil
+	}
+	return sortedByName(l.ingressLister.Ingresses(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) networkPolicies(_ context.Context, ns string) ([]*networkingv1.NetworkPolicy, error) {
+	return sortedByName(l.policyLister.NetworkPolicies(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) endpointSlices(_ context.Context, ns string) ([]*discoveryv1.EndpointSlice, error) {
+	return sortedByName(l.sliceLister.EndpointSlices(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) pdbs(_ context.Context, ns string) ([]*policyv1.PodDisruptionBudget, error) {
+	return sortedByName(l.pdbLister.PodDisruptionBudgets(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) hpas(_ context.Context, ns string) ([]*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if l.hpaLister == nil {
+		return nil, nil
+	}
+	return sortedByName(l.hpaLister.HorizontalPodAutoscalers(ns).List(labels.Everything()))
+}
+
+func (l *cacheLister) customObjects(_ context.Context, gvr schema.GroupVersionResource, ns string) ([]*unstructured.Unstructured, error) {
+	lister, ok := l.customListers[gvr]
+	if !ok {
+		return nil, nil
+	}
+	objects, err := lister.ByNamespace(ns).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			items = append(items, u)
+		}
+	}
+	return sortedByName(items, nil)
+}
+
+func pointers[T any](items []T) []*T {
+	ptrs := make([]*T, len(items))
+	for i := range items {
+		ptrs[i] = &items[i]
+	}
+	return ptrs
+}
+
+// sortedByName sorts lister results, which come from an unordered cache.
+func sortedByName[T metav1.Object](items []T, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(items, func(a, b T) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+	return items, nil
+}
@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"context"
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConvertReplicaSet converts a Kubernetes ReplicaSet to a model.ReplicaSet
+// without pods; AttachPods adds them.
+func ConvertReplicaSet(rs *appsv1.ReplicaSet) model.ReplicaSet {
+	// Default to 1 replica if not specified (Kubernetes ReplicaSet default)
+	replicas := int32(1)
+	if rs.Spec.Replicas != nil {
+		replicas = *rs.Spec.Replicas
+	}
+
+	return model.ReplicaSet{
+		Name:     rs.Name,
+		Replicas: replicas,
+		Ready:    rs.Status.ReadyReplicas,
+	}
+}
+
+// ConvertPod converts a Kubernetes Pod to a model.Pod. Restarts are summed
+// over every container, and the mounts come from the pod itself, so
+// StatefulSet pods mount their own ordinal's PVCs.
+func ConvertPod(pod *corev1.Pod) model.Pod {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+
+	phase := string(pod.Status.Phase)
+	if phase == "" {
+		phase = string(corev1.PodPending)
+	}
+
+	return model.Pod{
+		Name:         pod.Name,
+		Phase:        phase,
+		Restarts:     restarts,
+		Node:         pod.Spec.NodeName,
+		VolumeMounts: ExtractVolumeMounts(pod.Spec.Containers, pod.Spec.Volumes),
+	}
+}
+
+// ConvertNode converts a Kubernetes Node to a model.Node.
+func ConvertNode(node *corev1.Node) model.Node {
+	n := model.Node{
+		Name:   node.Name,
+		Zone:   node.Labels[corev1.LabelTopologyZone],
+		Region: node.Labels[corev1.LabelTopologyRegion],
+	}
+	if cpu, ok := node.Status.Capacity[corev1.ResourceCPU]; ok {
+		n.CPU = cpu.String()
+	}
+	if memory, ok := node.Status.Capacity[corev1.ResourceMemory]; ok {
+		n.Memory = memory.String()
+	}
+	if pods, ok := node.Status.Capacity[corev1.ResourcePods]; ok {
+		n.Pods = pods.String()
+	}
+	for _, t := range node.Spec.Taints {
+		n.Taints = append(n.Taints, model.Taint{Key: t.Key, Value: t.Value, Effect: string(t.Effect)})
+	}
+	return n
+}
+
+// AttachPods adds ReplicaSets to the Deployments that own them and Pods to
+// the ReplicaSets or workloads that own them, following controller
+// ownerReferences. Objects whose owner is not in the namespace are dropped,
+// and so are ReplicaSets scaled to zero with no pods left: they are old
+// Deployment revisions. Both lists must be sorted by name.
+func AttachPods(ns *model.Namespace, replicaSets []*appsv1.ReplicaSet, pods []*corev1.Pod) {
+	rsOwners := make(map[string]*model.Workload)
+	for _, rs := range replicaSets {
+		owner := metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != model.KindDeployment {
+			continue
+		}
+		w := ns.Workload(model.KindDeployment, owner.Name)
+		if w == nil {
+			continue
+		}
+		w.ReplicaSets = append(w.ReplicaSets, ConvertReplicaSet(rs))
+		rsOwners[rs.Name] = w
+	}
+
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			continue
+		}
+
+		if owner.Kind == model.KindReplicaSet {
+			w, ok := rsOwners[owner.Name]
+			if !ok {
+				continue
+			}
+			i := slices.IndexFunc(w.ReplicaSets, func(rs model.ReplicaSet) bool { return rs.Name == owner.Name })
+			w.ReplicaSets[i].Pods = append(w.ReplicaSets[i].Pods, ConvertPod(pod))
+			continue
+		}
+
+		if w := ns.Workload(owner.Kind, owner.Name); w != nil {
+			w.Pods = append(w.Pods, ConvertPod(pod))
+		}
+	}
+
+	for _, w := range rsOwners {
+		w.ReplicaSets = slices.DeleteFunc(w.ReplicaSets, func(rs model.ReplicaSet) bool {
+			return rs.Replicas == 0 && len(rs.Pods) == 0
+		})
+	}
+}
+
+func (c *Client) fetchNodes(ctx context.Context, cluster *model.Cluster) error {
+	nodes, err := c.lister.nodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		cluster.Nodes = append(cluster.Nodes, ConvertNode(node))
+	}
+	return nil
+}
+
+func (c *Client) fetchPods(ctx context.Context, nsName string, ns *model.Namespace) error {
+	replicaSets, err := c.lister.replicaSets(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	pods, err := c.lister.pods(ctx, nsName)
+	if err != nil {
+		return err
+	}
+	AttachPods(ns, replicaSets, pods)
+	return nil
+}
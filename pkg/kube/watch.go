@@ -0,0 +1,181 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watcher keeps informer caches of the resources a topology is built from.
+// After the initial LIST, informers only receive WATCH events, so the
+// topology can be rebuilt on every change without listing again.
+type Watcher struct {
+	cached         *Client
+	factory        informers.SharedInformerFactory
+	dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+	informers      []cache.SharedIndexInformer
+	opts           FetchOptions
+	changes        chan struct{}
+}
+
+// NewWatcher creates a watcher for the resources selected by opts. Call
+// Start before reading the topology.
+func (c *Client) NewWatcher(opts FetchOptions) (*Watcher, error) {
+	var factoryOpts []informers.SharedInformerOption
+	if opts.Namespace != "" {
+		factoryOpts = append(factoryOpts, informers.WithNamespace(opts.Namespace))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0, factoryOpts...)
+	lister, registered := newCacheLister(factory, opts, c.unavailableResources(opts.Namespace))
+
+	// CRD-backed resources are discovered once; the informers cannot follow
+	// CRDs that are installed after the watch starts.
+	crds := c.customResources()
+	var dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+	if len(crds) > 0 {
+		dynamicFactory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.dynamic, 0, opts.Namespace, nil)
+		registered = append(registered, lister.addCustomInformers(dynamicFactory, crds)...)
+	} else {
+		crds = map[string]schema.GroupVersionResource{}
+	}
+
+	w := &Watcher{
+		cached:         &Client{clientset: c.clientset, lister: lister, customAPI: crds},
+		factory:        factory,
+		dynamicFactory: dynamicFactory,
+		informers:      registered,
+		opts:           opts,
+		changes:        make(chan struct{}, 1),
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { w.notify() },
+		UpdateFunc: func(any, any) { w.notify() },
+		DeleteFunc: func(any) { w.notify() },
+	}
+	for _, informer := range registered {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// unavailableResources probes the resource types a read-only client may
+// not be allowed to list. Their informers would retry a forbidden LIST
+// forever and Start would never return. Like discovery, the probe runs once.
+func (c *Client) unavailableResources(namespace string) map[string]bool {
+	ctx := context.Background()
+	opts := metav1.ListOptions{Limit: 1}
+	probes := map[string]func() error{
+		resourceCronJobs: func() error {
+			_, err := c.clientset.BatchV1().CronJobs(namespace).List(ctx, opts)
+			return err
+		},
+		resourceJobs: func() error {
+			_, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, opts)
+			return err
+		},
+		resourceIngresses: func() error {
+			_, err := c.clientset.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+			return err
+		},
+		resourceAutoscaler: func() error {
+			_, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, opts)
+			return err
+		},
+	}
+
+	unavailable := make(map[string]bool)
+	for resource, probe := range probes {
+		if err := probe(); err != nil && skipUnavailable(err, resource, namespace) == nil {
+			unavailable[resource] = true
+		}
+	}
+	return unavailable
+}
+
+// notify records a change without blocking; pending changes coalesce.
+func (w *Watcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the informers until ctx is done and waits for the initial
+// LIST of every resource type to land in the cache.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.factory.Start(ctx.Done())
+	if w.dynamicFactory != nil {
+		w.dynamicFactory.Start(ctx.Done())
+	}
+
+	for _, informer := range w.informers {
+		if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return fmt.Errorf("timed out waiting for informer caches to sync")
+		}
+	}
+
+	// Events from the initial LIST are already part of the first topology
+	select {
+	case <-w.changes:
+	default:
+	}
+	return nil
+}
+
+// Topology builds the topology from the informer caches. It is safe for
+// concurrent use.
+func (w *Watcher) Topology(ctx context.Context) (*model.Cluster, error) {
+	return w.cached.FetchTopology(ctx, w.opts)
+}
+
+// Changes signals that a watched resource was added, updated or deleted
+// since the last receive.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Run calls onChange with the current topology, then again each time the
+// watched resources change, at most once per debounce interval: changes
+// arriving within the interval after the first one are folded into a
+// single rebuild. It returns when ctx is done or onChange fails.
+func (w *Watcher) Run(ctx context.Context, debounce time.Duration, onChange func(*model.Cluster) error) error {
+	if err := w.rebuild(ctx, onChange); err != nil {
+		return err
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.changes:
+			if pending == nil {
+				pending = time.After(debounce)
+			}
+		case <-pending:
+			pending = nil
+			if err := w.rebuild(ctx, onChange); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *Watcher) rebuild(ctx context.Context, onChange func(*model.Cluster) error) error {
+	cluster, err := w.Topology(ctx)
+	if err != nil {
+		return err
+	}
+	return onChange(cluster)
+}
@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+// DefaultReleaseName is the release name used when templating a chart
+// without an explicit one, matching helm template.
+const DefaultReleaseName = "release-name"
+
+// HelmChart describes a chart to template, equivalent to the arguments of
+// helm template.
+type HelmChart struct {
+	Chart       string   // Local chart directory or archive, or a repo/OCI reference
+	ValuesFiles []string // Values files merged in order, as with helm -f
+	ReleaseName string   // Defaults to DefaultReleaseName
+	Namespace   string   // Release namespace; defaults to DefaultNamespace
+}
+
+// RenderHelmChart templates a chart in-process and returns the rendered
+// manifests. Local charts are rendered without cluster or network access;
+// hooks and CRDs are left out because they are not part of the deployed
+// topology.
+func RenderHelmChart(ctx context.Context, hc HelmChart) ([]byte, error) {
+	settings := cli.New()
+
+	cfg := &action.Configuration{Log: func(string, ...any) {}}
+	install := action.NewInstall(cfg)
+	install.DryRun = true
+	install.DryRunOption = "client"
+	install.ClientOnly = true
+	install.Replace = true
+	install.DisableHooks = true
+	install.ReleaseName = hc.ReleaseName
+	if install.ReleaseName == "" {
+		install.ReleaseName = DefaultReleaseName
+	}
+	install.Namespace = hc.Namespace
+	if install.Namespace == "" {
+		install.Namespace = DefaultNamespace
+	}
+
+	chartPath, err := install.LocateChart(hc.Chart, settings)
+	if err != nil {
+		return nil, fmt.Errorf("locate chart %s: %w", hc.Chart, err)
+	}
+
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("load chart %s: %w", hc.Chart, err)
+	}
+
+	if deps := chrt.Metadata.Dependencies; len(deps) > 0 {
+		if err := action.CheckDependencies(chrt, deps); err != nil {
+			return nil, fmt.Errorf("chart %s: %w (run helm dependency build)", hc.Chart, err)
+		}
+	}
+
+	valueOpts := &values.Options{ValueFiles: hc.ValuesFiles}
+	vals, err := valueOpts.MergeValues(getter.All(settings))
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := install.RunWithContext(ctx, chrt, vals)
+	if err != nil {
+		return nil, fmt.Errorf("render chart %s: %w", hc.Chart, err)
+	}
+
+	return []byte(rel.Manifest), nil
+}
+
+// LoadHelmChart templates a chart and loads the rendered manifests. Only
+// templating failures are returned; document errors are reported by Err.
+func (l *Loader) LoadHelmChart(ctx context.Context, hc HelmChart) error {
+	if hc.Namespace == "" {
+		hc.Namespace = l.opts.Namespace
+	}
+
+	out, err := RenderHelmChart(ctx, hc)
+	if err != nil {
+		return err
+	}
+
+	l.LoadReader(bytes.NewReader(out), hc.Chart)
+	return nil
+}
@@ -0,0 +1,35 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// RenderKustomization builds a kustomization directory in-process and
+// returns the resulting manifests, equivalent to kustomize build.
+func RenderKustomization(dir string) ([]byte, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	resMap, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("build kustomization %s: %w", dir, err)
+	}
+
+	return resMap.AsYaml()
+}
+
+// LoadKustomization builds a kustomization and loads the resulting
+// manifests. Only build failures are returned; document errors are reported
+// by Err.
+func (l *Loader) LoadKustomization(dir string) error {
+	out, err := RenderKustomization(dir)
+	if err != nil {
+		return err
+	}
+
+	l.LoadReader(bytes.NewReader(out), dir)
+	return nil
+}
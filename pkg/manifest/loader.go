@@ -0,0 +1,635 @@
+// Package manifest builds a model.Cluster from Kubernetes manifest files
+// instead of a live cluster, so topology can be diagrammed before it is
+// applied.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/kube"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+// StdinPath is the path that selects standard input, as in kubectl -f -.
+const StdinPath = "-"
+
+// DefaultNamespace is used for objects that do not set metadata.namespace
+// when no namespace option is given, mirroring kubectl apply.
+const DefaultNamespace = "default"
+
+// Options controls how manifests are turned into a model.Cluster. The fields
+// mirror kube.FetchOptions so both sources produce the same topology.
+type Options struct {
+	// Namespace restricts the result to a single namespace. Objects without
+	// metadata.namespace are placed in it. Empty keeps every namespace and
+	// places such objects in DefaultNamespace.
+	Namespace              string
+	IncludeStorage         bool
+	IncludeNetworkPolicies bool
+	IncludePDBs            bool
+	IncludeConfigRefs      bool
+	// IncludeEndpoints keeps EndpointSlice documents and wires services
+	// from them. Pods and ReplicaSets are kept too, to find the workloads
+	// behind the endpoints.
+	IncludeEndpoints bool
+	// IncludePods keeps ReplicaSet and Pod documents, as found in
+	// "kubectl get -o yaml" dumps, and attaches them to their workloads.
+	IncludePods bool
+	// IncludeNodes keeps Node documents, for the node view.
+	IncludeNodes bool
+}
+
+// DocumentError reports a manifest document that could not be decoded.
+type DocumentError struct {
+	Source string // File path, or "<stdin>"
+	Index  int    // 1-based position of the document within the source
+	Err    error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("%s: document %d: %v", e.Source, e.Index, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// Loader accumulates Kubernetes objects from one or more manifest sources
+// and builds a model.Cluster from them.
+type Loader struct {
+	opts       Options
+	namespaces map[string]*model.Namespace
+	labels     map[string]map[string]string // Namespace object labels
+	errs       []error
+
+	// ReplicaSets, Pods and EndpointSlices by namespace, attached once
+	// every document is loaded
+	replicaSets    map[string][]*appsv1.ReplicaSet
+	pods           map[string][]*corev1.Pod
+	endpointSlices map[string][]*discoveryv1.EndpointSlice
+
+	// Autoscalers by namespace, attached to their targets the same way
+	hpas          map[string][]*autoscalingv2.HorizontalPodAutoscaler
+	scaledObjects map[string][]scaledObject
+
+	// ConfigMap and Secret names by namespace, to find missing references
+	configMaps map[string][]string
+	secrets    map[string][]string
+
+	nodes []model.Node
+}
+
+// scaledObject is a converted KEDA ScaledObject waiting for its target.
+type scaledObject struct {
+	target     model.Ref
+	autoscaler model.Autoscaler
+}
+
+// NewLoader creates a Loader with the given options.
+func NewLoader(opts Options) *Loader {
+	l := &Loader{
+		opts:       opts,
+		namespaces: make(map[string]*model.Namespace),
+		labels:     make(map[string]map[string]string),
+
+		replicaSets:    make(map[string][]*appsv1.ReplicaSet),
+		pods:           make(map[string][]*corev1.Pod),
+		endpointSlices: make(map[string][]*discoveryv1.EndpointSlice),
+
+		hpas:          make(map[string][]*autoscalingv2.HorizontalPodAutoscaler),
+		scaledObjects: make(map[string][]scaledObject),
+
+		configMaps: make(map[string][]string),
+		secrets:    make(map[string][]string),
+	}
+	if opts.Namespace != "" {
+		l.namespace(opts.Namespace)
+	}
+	return l
+}
+
+// Load reads every path (files, directories or StdinPath) and returns the
+// resulting cluster. All bad documents are reported together.
+func Load(paths []string, stdin io.Reader, opts Options) (*model.Cluster, error) {
+	l := NewLoader(opts)
+	if err := l.LoadPaths(paths, stdin); err != nil {
+		return nil, err
+	}
+
+	if err := l.Err(); err != nil {
+		return nil, err
+	}
+	return l.Cluster(), nil
+}
+
+// LoadPaths loads each path in turn, reading stdin for StdinPath.
+func (l *Loader) LoadPaths(paths []string, stdin io.Reader) error {
+	for _, path := range paths {
+		if path == StdinPath {
+			l.LoadReader(stdin, "<stdin>")
+			continue
+		}
+		if err := l.LoadPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadPath loads a manifest file, or every .yaml, .yml and .json file found
+// recursively under a directory. Only I/O failures are returned; document
+// errors are collected and reported by Err.
+func (l *Loader) LoadPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return l.loadFile(path)
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isManifestFile(p) {
+			return nil
+		}
+		return l.loadFile(p)
+	})
+}
+
+func (l *Loader) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	l.LoadReader(f, path)
+	return nil
+}
+
+// LoadReader loads a stream of YAML or JSON documents separated by ---.
+// source names the stream in error messages.
+func (l *Loader) LoadReader(r io.Reader, source string) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+
+	for index := 1; ; index++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			l.errs = append(l.errs, &DocumentError{Source: source, Index: index, Err: err})
+			return
+		}
+
+		if err := l.LoadDocument(doc); err != nil {
+			l.errs = append(l.errs, &DocumentError{Source: source, Index: index, Err: err})
+		}
+	}
+}
+
+// LoadDocument decodes a single Kubernetes object, or a List of objects, and
+// adds it to the topology. Empty and comment-only documents are ignored, as
+// are kinds that are not part of the diagram.
+func (l *Loader) LoadDocument(doc []byte) error {
+	data, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var typeMeta struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return err
+	}
+
+	if typeMeta.Kind == "" {
+		return fmt.Errorf("object has no kind")
+	}
+
+	if strings.HasSuffix(typeMeta.Kind, "List") {
+		for i, item := range typeMeta.Items {
+			if err := l.LoadDocument(item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	return l.decode(typeMeta.Kind, data)
+}
+
+func (l *Loader) decode(kind string, data []byte) error {
+	switch kind {
+	case "Namespace":
+		var obj corev1.Namespace
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if l.opts.Namespace == "" || l.opts.Namespace == obj.Name {
+			l.namespace(obj.Name)
+			l.labels[obj.Name] = obj.Labels
+		}
+	case "Node":
+		if !l.opts.IncludeNodes {
+			return nil
+		}
+		var obj corev1.Node
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		l.nodes = append(l.nodes, kube.ConvertNode(&obj))
+	case "Deployment":
+		var obj appsv1.Deployment
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.Deployments = append(ns.Deployments, kube.ConvertDeployment(&obj))
+		}
+	case "StatefulSet":
+		var obj appsv1.StatefulSet
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.StatefulSets = append(ns.StatefulSets, kube.ConvertStatefulSet(&obj))
+		}
+	case "DaemonSet":
+		var obj appsv1.DaemonSet
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.DaemonSets = append(ns.DaemonSets, kube.ConvertDaemonSet(&obj))
+		}
+	case model.KindCronJob:
+		var obj batchv1.CronJob
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.CronJobs = append(ns.CronJobs, kube.ConvertCronJob(&obj))
+		}
+	case model.KindJob:
+		var obj batchv1.Job
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.Jobs = append(ns.Jobs, kube.ConvertJob(&obj))
+		}
+	case model.KindReplicaSet:
+		if !l.opts.IncludePods && !l.opts.IncludeEndpoints {
+			return nil
+		}
+		var obj appsv1.ReplicaSet
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.replicaSets[ns.Name] = append(l.replicaSets[ns.Name], &obj)
+		}
+	case model.KindPod:
+		if !l.opts.IncludePods && !l.opts.IncludeEndpoints {
+			return nil
+		}
+		var obj corev1.Pod
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.pods[ns.Name] = append(l.pods[ns.Name], &obj)
+		}
+	case "Service":
+		var obj corev1.Service
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.Services = append(ns.Services, kube.ConvertService(&obj))
+		}
+	default:
+		return l.decodeConfigAndStorage(kind, data)
+	}
+	return nil
+}
+
+func (l *Loader) decodeConfigAndStorage(kind string, data []byte) error {
+	switch kind {
+	case model.KindConfigMap:
+		var obj corev1.ConfigMap
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.configMaps[ns.Name] = append(l.configMaps[ns.Name], obj.Name)
+			if !kube.IsSystemConfigMap(obj.Name) {
+				ns.ConfigMaps++
+			}
+		}
+	case model.KindSecret:
+		var obj corev1.Secret
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.secrets[ns.Name] = append(l.secrets[ns.Name], obj.Name)
+			if !kube.IsSystemSecret(obj.Name, obj.Type) {
+				ns.Secrets++
+			}
+		}
+	case "PersistentVolumeClaim":
+		if !l.opts.IncludeStorage {
+			return nil
+		}
+		var obj corev1.PersistentVolumeClaim
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.PVCs = append(ns.PVCs, kube.ConvertPVC(&obj))
+		}
+	default:
+		return l.decodeNetworking(kind, data)
+	}
+	return nil
+}
+
+func (l *Loader) decodeNetworking(kind string, data []byte) error {
+	switch kind {
+	case "Ingress":
+		var obj networkingv1.Ingress
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.Ingresses = append(ns.Ingresses, kube.ConvertIngress(&obj))
+		}
+	case "EndpointSlice":
+		if !l.opts.IncludeEndpoints {
+			return nil
+		}
+		var obj discoveryv1.EndpointSlice
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.endpointSlices[ns.Name] = append(l.endpointSlices[ns.Name], &obj)
+		}
+	case model.KindNetworkPolicy:
+		if !l.opts.IncludeNetworkPolicies {
+			return nil
+		}
+		var obj networkingv1.NetworkPolicy
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.NetworkPolicies = append(ns.NetworkPolicies, kube.ConvertNetworkPolicy(&obj))
+		}
+	default:
+		return l.decodeGatewayAPI(kind, data)
+	}
+	return nil
+}
+
+// decodeGatewayAPI decodes Gateway API objects. Routes are converted after
+// their namespace is resolved, because references without a namespace
+// point into the route's own namespace.
+func (l *Loader) decodeGatewayAPI(kind string, data []byte) error {
+	switch kind {
+	case model.KindGateway:
+		var obj gatewayv1.Gateway
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.Gateways = append(ns.Gateways, kube.ConvertGateway(&obj))
+		}
+	case model.KindHTTPRoute:
+		var obj gatewayv1.HTTPRoute
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			obj.Namespace = ns.Name
+			ns.Routes = append(ns.Routes, kube.ConvertHTTPRoute(&obj))
+		}
+	case model.KindGRPCRoute:
+		var obj gatewayv1alpha2.GRPCRoute
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			obj.Namespace = ns.Name
+			ns.Routes = append(ns.Routes, kube.ConvertGRPCRoute(&obj))
+		}
+	case model.KindTCPRoute:
+		var obj gatewayv1alpha2.TCPRoute
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			obj.Namespace = ns.Name
+			ns.Routes = append(ns.Routes, kube.ConvertTCPRoute(&obj))
+		}
+	case kube.KindReferenceGrant:
+		var obj gatewayv1beta1.ReferenceGrant
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.ReferenceGrants = append(ns.ReferenceGrants, kube.ConvertReferenceGrant(&obj))
+		}
+	default:
+		return l.decodeAutoscaling(kind, data)
+	}
+	return nil
+}
+
+// decodeAutoscaling decodes HPAs and KEDA ScaledObjects. They are attached
+// to their target workloads once every document is loaded, since targets
+// may come later.
+func (l *Loader) decodeAutoscaling(kind string, data []byte) error {
+	switch kind {
+	case model.KindHPA:
+		var obj autoscalingv2.HorizontalPodAutoscaler
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			l.hpas[ns.Name] = append(l.hpas[ns.Name], &obj)
+		}
+	case model.KindScaledObject:
+		var obj unstructured.Unstructured
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		a, target, err := kube.ConvertScaledObject(&obj)
+		if err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.GetNamespace()); ns != nil {
+			l.scaledObjects[ns.Name] = append(l.scaledObjects[ns.Name], scaledObject{target: target, autoscaler: a})
+		}
+	default:
+		return l.decodePolicy(kind, data)
+	}
+	return nil
+}
+
+func (l *Loader) decodePolicy(kind string, data []byte) error {
+	switch kind {
+	case model.KindPDB:
+		if !l.opts.IncludePDBs {
+			return nil
+		}
+		var obj policyv1.PodDisruptionBudget
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if ns := l.objectNamespace(obj.Namespace); ns != nil {
+			ns.PDBs = append(ns.PDBs, kube.ConvertPDB(&obj))
+		}
+	}
+	return nil
+}
+
+// objectNamespace returns the namespace an object belongs to, or nil if the
+// object is filtered out by Options.Namespace.
+func (l *Loader) objectNamespace(name string) *model.Namespace {
+	if name == "" {
+		name = l.opts.Namespace
+	}
+	if name == "" {
+		name = DefaultNamespace
+	}
+	if l.opts.Namespace != "" && name != l.opts.Namespace {
+		return nil
+	}
+	return l.namespace(name)
+}
+
+func (l *Loader) namespace(name string) *model.Namespace {
+	ns, ok := l.namespaces[name]
+	if !ok {
+		ns = &model.Namespace{Name: name}
+		l.namespaces[name] = ns
+	}
+	return ns
+}
+
+// Err returns every document error collected so far, or nil.
+func (l *Loader) Err() error {
+	return errors.Join(l.errs...)
+}
+
+// Cluster builds the topology from the loaded objects. Namespaces and the
+// resources inside them are sorted by name, matching the order returned by
+// the API server so output is identical to Client.FetchTopology.
+func (l *Loader) Cluster() *model.Cluster {
+	cluster := &model.Cluster{Name: "cluster"}
+
+	names := make([]string, 0, len(l.namespaces))
+	for name := range l.namespaces {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		ns := l.namespaces[name]
+		sortByName(ns.Deployments, func(w model.Workload) string { return w.Name })
+		sortByName(ns.StatefulSets, func(w model.Workload) string { return w.Name })
+		sortByName(ns.DaemonSets, func(w model.Workload) string { return w.Name })
+		sortByName(ns.CronJobs, func(w model.Workload) string { return w.Name })
+		sortByName(ns.Jobs, func(w model.Workload) string { return w.Name })
+		sortByName(ns.Services, func(s model.Service) string { return s.Name })
+		sortByName(ns.PVCs, func(p model.PVC) string { return p.Name })
+		sortByName(ns.Ingresses, func(i model.Ingress) string { return i.Name })
+		sortByName(ns.Gateways, func(g model.Gateway) string { return g.Name })
+		sortByName(ns.Routes, func(r model.Route) string { return r.Kind + "/" + r.Name })
+		sortByName(ns.ReferenceGrants, func(g model.ReferenceGrant) string { return g.Name })
+		sortByName(ns.NetworkPolicies, func(p model.NetworkPolicy) string { return p.Name })
+		sortByName(ns.PDBs, func(p model.PDB) string { return p.Name })
+		if l.opts.IncludeNetworkPolicies {
+			ns.Labels = kube.NamespaceLabels(name, l.labels[name])
+			ns.ResolveNetworkAccess()
+		}
+		if l.opts.IncludeConfigRefs {
+			ns.ResolveConfigRefs(l.configMaps[name], l.secrets[name])
+		} else {
+			ns.ClearConfigRefs()
+		}
+		for _, hpa := range l.hpas[name] {
+			kube.AttachHPA(ns, hpa)
+		}
+		for _, so := range l.scaledObjects[name] {
+			kube.AttachAutoscaler(ns, so.target, so.autoscaler)
+		}
+		sortByName(l.replicaSets[name], func(rs *appsv1.ReplicaSet) string { return rs.Name })
+		sortByName(l.pods[name], func(p *corev1.Pod) string { return p.Name })
+		if l.opts.IncludePods {
+			kube.AttachPods(ns, l.replicaSets[name], l.pods[name])
+		}
+		if l.opts.IncludeEndpoints {
+			sortByName(l.endpointSlices[name], func(s *discoveryv1.EndpointSlice) string { return s.Name })
+			kube.AttachEndpoints(ns, l.endpointSlices[name], l.replicaSets[name], l.pods[name])
+		}
+		cluster.Namespaces = append(cluster.Namespaces, *ns)
+	}
+
+	sortByName(l.nodes, func(n model.Node) string { return n.Name })
+	cluster.Nodes = l.nodes
+
+	kube.ResolveReferenceGrants(cluster)
+	return cluster
+}
+
+func sortByName[T any](items []T, name func(T) string) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		return strings.Compare(name(a), name(b))
+	})
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
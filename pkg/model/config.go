@@ -0,0 +1,68 @@
+package model
+
+import "slices"
+
+// ConfigRef is a ConfigMap or Secret that a workload's pod template uses.
+type ConfigRef struct {
+	Kind string   `json:"kind"` // ConfigMap, Secret
+	Name string   `json:"name"`
+	Via  []string `json:"via"` // Any of the Via constants, in order of first use
+	// Optional is set when every use is marked optional, so pods start
+	// without the object.
+	Optional bool `json:"optional,omitempty"`
+	// Missing marks a required object that does not exist in the namespace.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// ConfigRef.Via values.
+const (
+	ViaEnv             = "env"             // A single key in env valueFrom
+	ViaEnvFrom         = "envFrom"         // Every key as environment variables
+	ViaVolume          = "volume"          // A volume, or a projected volume source
+	ViaImagePullSecret = "imagePullSecret" // Registry credentials
+)
+
+// ConfigObjects returns every ConfigMap and Secret used by the workloads of
+// the namespace, in order of first use. An object is missing if any
+// workload requires it and it does not exist.
+func (ns *Namespace) ConfigObjects() []ConfigRef {
+	var objects []ConfigRef
+	for _, w := range ns.AllWorkloads() {
+		for _, ref := range w.ConfigRefs {
+			i := slices.IndexFunc(objects, func(o ConfigRef) bool { return o.Kind == ref.Kind && o.Name == ref.Name })
+			if i < 0 {
+				objects = append(objects, ConfigRef{Kind: ref.Kind, Name: ref.Name, Missing: ref.Missing})
+				continue
+			}
+			objects[i].Missing = objects[i].Missing || ref.Missing
+		}
+	}
+	return objects
+}
+
+// ResolveConfigRefs marks the required references of every workload whose
+// object is not among the namespace's ConfigMaps and Secrets.
+func (ns *Namespace) ResolveConfigRefs(configMaps, secrets []string) {
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			for j := range workloads[i].ConfigRefs {
+				ref := &workloads[i].ConfigRefs[j]
+				names := configMaps
+				if ref.Kind == KindSecret {
+					names = secrets
+				}
+				ref.Missing = !ref.Optional && !slices.Contains(names, ref.Name)
+			}
+		}
+	}
+}
+
+// ClearConfigRefs drops the ConfigMap and Secret references of every
+// workload.
+func (ns *Namespace) ClearConfigRefs() {
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			workloads[i].ConfigRefs = nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ServiceCall is a service a workload's environment names by its cluster
+// DNS name, e.g. API_URL=http://api.shop.svc.cluster.local:8080.
+type ServiceCall struct {
+	Namespace string   `json:"namespace"`
+	Service   string   `json:"service"`
+	Env       []string `json:"env"` // Variables naming the service, in order
+}
+
+// serviceDNSPattern matches "<service>.<namespace>.svc", optionally
+// followed by the default cluster domain.
+var serviceDNSPattern = regexp.MustCompile(`\b([a-z0-9](?:[-a-z0-9]*[a-z0-9])?)\.([a-z0-9](?:[-a-z0-9]*[a-z0-9])?)\.svc(?:\.cluster\.local)?\b`)
+
+// ServiceDNSNames returns the services named by cluster DNS names anywhere
+// in s, such as a URL or a comma-separated host list, each once.
+func ServiceDNSNames(s string) []Ref {
+	var refs []Ref
+	for _, m := range serviceDNSPattern.FindAllStringSubmatch(s, -1) {
+		ref := Ref{Kind: KindService, Namespace: m[2], Name: m[1]}
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ParseServiceDNS returns the service a hostname such as
+// "api.shop.svc.cluster.local" resolves to inside the cluster. It reports
+// false for hostnames outside the cluster.
+func ParseServiceDNS(host string) (Ref, bool) {
+	host = strings.TrimSuffix(host, ".")
+	m := serviceDNSPattern.FindStringSubmatch(host)
+	if m == nil || m[0] != host {
+		return Ref{}, false
+	}
+	return Ref{Kind: KindService, Namespace: m[2], Name: m[1]}, true
+}
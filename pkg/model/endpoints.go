@@ -0,0 +1,33 @@
+package model
+
+import "fmt"
+
+// Endpoints is what a service's EndpointSlices route to, grouped by the
+// workload owning each endpoint's pod.
+type Endpoints struct {
+	Ready   int32            `json:"ready"`
+	Total   int32            `json:"total"`
+	Targets []EndpointTarget `json:"targets,omitempty"`
+}
+
+// EndpointTarget is a workload, a pod without a workload, or a bare
+// address behind a service, with the readiness of its endpoints.
+type EndpointTarget struct {
+	Kind  string `json:"kind"` // Workload kind, Pod, or Address
+	Name  string `json:"name"` // The address itself for Address targets
+	Ready int32  `json:"ready"`
+	Total int32  `json:"total"`
+}
+
+// FormatReady formats endpoint readiness as "2/3 ready".
+func FormatReady(ready, total int32) string {
+	return fmt.Sprintf("%d/%d ready", ready, total)
+}
+
+// ClearEndpoints drops the EndpointSlice wiring of every service, so they
+// are connected by selector again.
+func (ns *Namespace) ClearEndpoints() {
+	for i := range ns.Services {
+		ns.Services[i].Endpoints = nil
+	}
+}
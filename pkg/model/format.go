@@ -12,11 +12,88 @@ import (
 func FormatMountLabel(mounts []VolumeMount) string {
 	labels := make([]string, len(mounts))
 	for i, m := range mounts {
-		accessMode := "rw"
-		if m.ReadOnly {
-			accessMode = "ro"
-		}
-		labels[i] = fmt.Sprintf("%s (%s)", m.MountPath, accessMode)
+		labels[i] = FormatMount(m)
 	}
 	return strings.Join(labels, "\\n")
 }
+
+// FormatMount formats a single volume mount as "/var/log/app (rw)".
+func FormatMount(m VolumeMount) string {
+	accessMode := "rw"
+	if m.ReadOnly {
+		accessMode = "ro"
+	}
+	return fmt.Sprintf("%s (%s)", m.MountPath, accessMode)
+}
+
+// FormatRoute formats an ingress route as "host/path", using "*" for rules
+// that match every host.
+func FormatRoute(r IngressRoute) string {
+	host := r.Host
+	if host == "" {
+		host = "*"
+	}
+	path := r.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return host + path
+}
+
+// FormatTaint formats a taint as "key=value:Effect", or "key:Effect" when
+// it has no value.
+func FormatTaint(t Taint) string {
+	if t.Value == "" {
+		return t.Key + ":" + t.Effect
+	}
+	return t.Key + "=" + t.Value + ":" + t.Effect
+}
+
+// FormatPort formats a service port as "80→8080/TCP", appending the node
+// port as "80→8080/TCP (node 30080)". A named target port is resolved
+// against the workload's container ports; without a workload, or when no
+// container declares it, the name is kept, as in "80→http/TCP".
+func FormatPort(p Port, w *Workload) string {
+	target := p.TargetPortName
+	if target == "" {
+		target = fmt.Sprint(p.TargetPort)
+	} else if w != nil {
+		if port := w.ResolvePort(p); port != 0 {
+			target = fmt.Sprint(port)
+		}
+	}
+
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "TCP"
+	}
+
+	label := fmt.Sprintf("%d→%s/%s", p.Port, target, protocol)
+	if p.NodePort != 0 {
+		label += fmt.Sprintf(" (node %d)", p.NodePort)
+	}
+	return label
+}
+
+// FormatPorts formats every port of a service with FormatPort, one per
+// line.
+func FormatPorts(ports []Port, w *Workload) string {
+	formatted := make([]string, len(ports))
+	for i, p := range ports {
+		formatted[i] = FormatPort(p, w)
+	}
+	return strings.Join(formatted, "\n")
+}
+
+// FormatOrdinals formats the pod names of a StatefulSet as "web-{0..2}",
+// or "web-0" for a single replica. It is empty without replicas.
+func FormatOrdinals(w *Workload) string {
+	switch w.Replicas {
+	case 0:
+		return ""
+	case 1:
+		return w.Name + "-0"
+	default:
+		return fmt.Sprintf("%s-{0..%d}", w.Name, w.Replicas-1)
+	}
+}
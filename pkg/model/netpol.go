@@ -0,0 +1,443 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Policy types, as in NetworkPolicy.PolicyTypes.
+const (
+	PolicyIngress = "Ingress"
+	PolicyEgress  = "Egress"
+)
+
+// Workload.NetworkAccess values.
+const (
+	AccessIsolated = "isolated" // No ingress and no egress is allowed
+	AccessOpen     = "open"     // All ingress and all egress is allowed
+)
+
+// NamespaceNameLabel is set on every namespace by the API server, so
+// namespaceSelectors can match namespaces by name.
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// Matches reports whether the selector matches a set of labels, with
+// Kubernetes semantics: an empty selector matches everything, and an
+// invalid one matches nothing.
+func (s *LabelSelector) Matches(set map[string]string) bool {
+	return s.Selector().Matches(labels.Set(set))
+}
+
+// Selector converts the selector for repeated matching. Invalid selectors
+// match nothing.
+func (s *LabelSelector) Selector() labels.Selector {
+	selector := &metav1.LabelSelector{MatchLabels: s.MatchLabels}
+	for _, r := range s.MatchExpressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      r.Key,
+			Operator: metav1.LabelSelectorOperator(r.Operator),
+			Values:   r.Values,
+		})
+	}
+
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return labels.Nothing()
+	}
+	return parsed
+}
+
+// PolicyFlow is traffic between two resources of a namespace that its
+// network policies explicitly allow. Ports is empty when every port is
+// allowed.
+type PolicyFlow struct {
+	From  Ref
+	To    Ref
+	Ports []string
+}
+
+// PolicyFlows lists the traffic the namespace's network policies allow:
+// between workloads where at least one side is isolated by a policy, and
+// between workloads and ipBlock peers. Traffic between workloads that no
+// policy selects is allowed too, but not listed.
+func (ns *Namespace) PolicyFlows() []PolicyFlow {
+	var flows []PolicyFlow
+	compiled := ns.compiledWorkloads()
+	nsLabels := labels.Set(ns.Labels)
+
+	for i := range compiled {
+		from := &compiled[i]
+		for j := range compiled {
+			to := &compiled[j]
+			if i == j {
+				continue
+			}
+			if ports, ok := allowed(from, nsLabels, to, nsLabels, true); ok {
+				flows = append(flows, PolicyFlow{From: from.ref, To: to.ref, Ports: ports})
+			}
+		}
+	}
+
+	workloads := ns.AllWorkloads()
+	for i := range workloads {
+		w := &workloads[i]
+		for _, f := range ns.ipBlockFlows(w, PolicyIngress) {
+			flows = append(flows, PolicyFlow{From: f.From, To: ns.workloadRef(w), Ports: f.Ports})
+		}
+		for _, f := range ns.ipBlockFlows(w, PolicyEgress) {
+			flows = append(flows, PolicyFlow{From: ns.workloadRef(w), To: f.From, Ports: f.Ports})
+		}
+	}
+
+	return flows
+}
+
+// CrossNamespaceFlows lists the traffic network policies allow between
+// workloads of different namespaces, allowed by the egress policies of the
+// source and the ingress policies of the destination like PolicyFlows. A
+// flow is only listed when a namespaceSelector names the other side, so
+// rules allowing every peer do not connect every pair of namespaces.
+//
+// Every namespace pair is checked, so policies are compiled once per
+// namespace instead of once per pair of workloads.
+func (c *Cluster) CrossNamespaceFlows() []PolicyFlow {
+	compiled := make([][]compiledWorkload, len(c.Namespaces))
+	for i := range c.Namespaces {
+		compiled[i] = c.Namespaces[i].compiledWorkloads()
+	}
+
+	var flows []PolicyFlow
+	for i := range c.Namespaces {
+		src := &c.Namespaces[i]
+		for j := range c.Namespaces {
+			dst := &c.Namespaces[j]
+			if i == j || (len(src.NetworkPolicies) == 0 && len(dst.NetworkPolicies) == 0) {
+				continue
+			}
+			srcLabels, dstLabels := labels.Set(src.Labels), labels.Set(dst.Labels)
+			for k := range compiled[i] {
+				from := &compiled[i][k]
+				for l := range compiled[j] {
+					to := &compiled[j][l]
+					if ports, ok := allowed(from, srcLabels, to, dstLabels, false); ok {
+						flows = append(flows, PolicyFlow{From: from.ref, To: to.ref, Ports: ports})
+					}
+				}
+			}
+		}
+	}
+
+	return flows
+}
+
+// compiledWorkload is a workload with the egress and ingress rules of the
+// policies selecting it, compiled for matching peers.
+type compiledWorkload struct {
+	ref     Ref
+	labels  labels.Set
+	egress  compiledRules
+	ingress compiledRules
+}
+
+// compiledRules are the rules of the policies selecting a workload for one
+// direction. ipBlock peers are dropped: they match no workload.
+type compiledRules struct {
+	selected bool
+	rules    []*compiledRule
+}
+
+type compiledRule struct {
+	allPeers bool // The rule has no peers and allows every one
+	peers    []compiledPeer
+	ports    []string // Formatted ports; empty for every port
+}
+
+type compiledPeer struct {
+	namespace labels.Selector // nil for the policy's own namespace
+	pod       labels.Selector // nil for every pod of the namespaces
+}
+
+// compiledWorkloads compiles the namespace's policies once and collects,
+// for every workload, the rules of the policies selecting it.
+func (ns *Namespace) compiledWorkloads() []compiledWorkload {
+	type compiledPolicy struct {
+		pod             labels.Selector
+		ingress, egress bool
+		ingressRules    []*compiledRule
+		egressRules     []*compiledRule
+	}
+	policies := make([]compiledPolicy, 0, len(ns.NetworkPolicies))
+	for _, p := range ns.NetworkPolicies {
+		policies = append(policies, compiledPolicy{
+			pod:          p.PodSelector.Selector(),
+			ingress:      slices.Contains(p.PolicyTypes, PolicyIngress),
+			egress:       slices.Contains(p.PolicyTypes, PolicyEgress),
+			ingressRules: compileRules(p.Ingress),
+			egressRules:  compileRules(p.Egress),
+		})
+	}
+
+	workloads := ns.AllWorkloads()
+	compiled := make([]compiledWorkload, 0, len(workloads))
+	for i := range workloads {
+		w := &workloads[i]
+		cw := compiledWorkload{ref: ns.workloadRef(w), labels: labels.Set(w.TemplateLabels())}
+		for _, p := range policies {
+			if !p.pod.Matches(cw.labels) {
+				continue
+			}
+			if p.ingress {
+				cw.ingress.selected = true
+				cw.ingress.rules = append(cw.ingress.rules, p.ingressRules...)
+			}
+			if p.egress {
+				cw.egress.selected = true
+				cw.egress.rules = append(cw.egress.rules, p.egressRules...)
+			}
+		}
+		compiled = append(compiled, cw)
+	}
+	return compiled
+}
+
+func compileRules(rules []PolicyRule) []*compiledRule {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		cr := &compiledRule{allPeers: len(rule.Peers) == 0}
+		for _, peer := range rule.Peers {
+			if peer.IPBlock != nil {
+				continue
+			}
+			var cp compiledPeer
+			if peer.NamespaceSelector != nil {
+				cp.namespace = peer.NamespaceSelector.Selector()
+			}
+			if peer.PodSelector != nil {
+				cp.pod = peer.PodSelector.Selector()
+			}
+			cr.peers = append(cr.peers, cp)
+		}
+		for _, p := range rule.Ports {
+			if formatted := FormatPolicyPort(p); !slices.Contains(cr.ports, formatted) {
+				cr.ports = append(cr.ports, formatted)
+			}
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled
+}
+
+// allow reports whether any rule allows a workload with the given labels in
+// a namespace with the given labels, and the ports those rules allow; nil
+// ports means every port. sameNamespace is set when the workload is in the
+// policy's own namespace, the only one peers without a namespaceSelector
+// select. named reports whether a namespaceSelector, rather than a rule
+// allowing every peer, selected the workload.
+func (r *compiledRules) allow(sameNamespace bool, nsLabels, podLabels labels.Set) (ports []string, allowed, named bool) {
+	allPorts := false
+	for _, rule := range r.rules {
+		matched := rule.allPeers
+		for _, peer := range rule.peers {
+			if peer.namespace == nil && !sameNamespace {
+				continue
+			}
+			if peer.namespace != nil && !peer.namespace.Matches(nsLabels) {
+				continue
+			}
+			if peer.pod == nil || peer.pod.Matches(podLabels) {
+				matched = true
+				named = named || peer.namespace != nil
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		allowed = true
+		if len(rule.ports) == 0 {
+			allPorts = true
+		}
+		for _, p := range rule.ports {
+			if !slices.Contains(ports, p) {
+				ports = append(ports, p)
+			}
+		}
+	}
+	if allPorts {
+		ports = nil
+	}
+	return ports, allowed, named
+}
+
+// allowed reports whether traffic from one workload to another is allowed
+// by both the egress policies of the source and the ingress policies of the
+// destination, and at least one of them is isolated. Ports come from the
+// destination's rules, or the source's when only it is isolated. Across
+// namespaces, a namespaceSelector on either side must also select the other
+// one.
+func allowed(from *compiledWorkload, fromNs labels.Set, to *compiledWorkload, toNs labels.Set, sameNamespace bool) ([]string, bool) {
+	if !from.egress.selected && !to.ingress.selected {
+		return nil, false
+	}
+
+	egressPorts, egressOK, egressNamed := from.egress.allow(sameNamespace, toNs, to.labels)
+	ingressPorts, ingressOK, ingressNamed := to.ingress.allow(sameNamespace, fromNs, from.labels)
+	if !sameNamespace && !egressNamed && !ingressNamed {
+		return nil, false
+	}
+	if (from.egress.selected && !egressOK) || (to.ingress.selected && !ingressOK) {
+		return nil, false
+	}
+
+	if to.ingress.selected {
+		return ingressPorts, true
+	}
+	return egressPorts, true
+}
+
+// ResolveNetworkAccess sets NetworkAccess on every workload of the
+// namespace from its network policies.
+func (ns *Namespace) ResolveNetworkAccess() {
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			workloads[i].NetworkAccess = ns.networkAccess(&workloads[i])
+		}
+	}
+}
+
+// ClearNetworkPolicies drops the network policy layer from the namespace.
+func (ns *Namespace) ClearNetworkPolicies() {
+	ns.NetworkPolicies = nil
+	ns.Labels = nil
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			workloads[i].NetworkAccess = ""
+		}
+	}
+}
+
+func (ns *Namespace) networkAccess(w *Workload) string {
+	ingress := ns.directionAccess(w, PolicyIngress)
+	egress := ns.directionAccess(w, PolicyEgress)
+	switch {
+	case ingress == AccessIsolated && egress == AccessIsolated:
+		return AccessIsolated
+	case ingress == AccessOpen && egress == AccessOpen:
+		return AccessOpen
+	default:
+		return ""
+	}
+}
+
+// directionAccess returns AccessIsolated when policies select the workload
+// for a direction but allow nothing, AccessOpen when they allow every peer
+// on every port (or no policy selects it), and "" otherwise.
+func (ns *Namespace) directionAccess(w *Workload, policyType string) string {
+	selected, rules := ns.policyRules(w, policyType)
+	if !selected {
+		return AccessOpen
+	}
+	for _, rule := range rules {
+		if len(rule.Peers) == 0 && len(rule.Ports) == 0 {
+			return AccessOpen
+		}
+	}
+	if len(rules) == 0 {
+		return AccessIsolated
+	}
+	return ""
+}
+
+// policyRules reports whether any policy of the given type selects the
+// workload, and returns the rules of those policies.
+func (ns *Namespace) policyRules(w *Workload, policyType string) (bool, []PolicyRule) {
+	selected := false
+	var rules []PolicyRule
+	for _, p := range ns.NetworkPolicies {
+		if !slices.Contains(p.PolicyTypes, policyType) || !p.PodSelector.Matches(w.TemplateLabels()) {
+			continue
+		}
+		selected = true
+		if policyType == PolicyIngress {
+			rules = append(rules, p.Ingress...)
+		} else {
+			rules = append(rules, p.Egress...)
+		}
+	}
+	return selected, rules
+}
+
+// ipBlockFlows returns a flow per ipBlock peer of the rules selecting the
+// workload for the given direction, with the ipBlock as From.
+func (ns *Namespace) ipBlockFlows(w *Workload, policyType string) []PolicyFlow {
+	_, rules := ns.policyRules(w, policyType)
+
+	var flows []PolicyFlow
+	for _, rule := range rules {
+		var ports []string
+		for _, p := range rule.Ports {
+			ports = append(ports, FormatPolicyPort(p))
+		}
+		for _, peer := range rule.Peers {
+			if peer.IPBlock == nil {
+				continue
+			}
+			flows = append(flows, PolicyFlow{
+				From:  Ref{Kind: KindIPBlock, Namespace: ns.Name, Name: peer.IPBlock.CIDR},
+				Ports: ports,
+			})
+		}
+	}
+	return flows
+}
+
+func (ns *Namespace) workloadRef(w *Workload) Ref {
+	return Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name}
+}
+
+// FormatPolicyPort formats a policy port as "8080/TCP", "8000-8080/TCP" or
+// "TCP" when every port of the protocol is allowed.
+func FormatPolicyPort(p PolicyPort) string {
+	switch {
+	case p.Port == "":
+		return p.Protocol
+	case p.EndPort != 0:
+		return fmt.Sprintf("%s-%d/%s", p.Port, p.EndPort, p.Protocol)
+	default:
+		return p.Port + "/" + p.Protocol
+	}
+}
+
+// IPBlocks lists the distinct ipBlock peers of the namespace's policies, in
+// the order they first appear. Blocks with the same CIDR are merged.
+func (ns *Namespace) IPBlocks() []IPBlock {
+	var blocks []IPBlock
+	for _, p := range ns.NetworkPolicies {
+		for _, rule := range slices.Concat(p.Ingress, p.Egress) {
+			for _, peer := range rule.Peers {
+				if peer.IPBlock != nil {
+					blocks = mergeIPBlock(blocks, *peer.IPBlock)
+				}
+			}
+		}
+	}
+	return blocks
+}
+
+func mergeIPBlock(blocks []IPBlock, block IPBlock) []IPBlock {
+	for i := range blocks {
+		if blocks[i].CIDR != block.CIDR {
+			continue
+		}
+		for _, except := range block.Except {
+			if !slices.Contains(blocks[i].Except, except) {
+				blocks[i].Except = append(blocks[i].Except, except)
+			}
+		}
+		return blocks
+	}
+	return append(blocks, IPBlock{CIDR: block.CIDR, Except: slices.Clone(block.Except)})
+}
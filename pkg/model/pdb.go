@@ -0,0 +1,59 @@
+package model
+
+import "k8s.io/apimachinery/pkg/util/intstr"
+
+// PDB is a PodDisruptionBudget.
+type PDB struct {
+	Name string `json:"name"`
+	// Selector is nil when the budget selects no pods; an empty selector
+	// selects every pod of the namespace.
+	Selector *LabelSelector `json:"selector,omitempty"`
+	// MinAvailable and MaxUnavailable are pod counts or percentages, e.g.
+	// "2" or "25%". At most one is set.
+	MinAvailable   string `json:"minAvailable,omitempty"`
+	MaxUnavailable string `json:"maxUnavailable,omitempty"`
+	// DisruptionsAllowed is reported by the disruption controller. It is
+	// nil for budgets that were never evaluated, such as manifests.
+	DisruptionsAllowed *int32 `json:"disruptionsAllowed,omitempty"`
+}
+
+// Budget returns the budget as "minAvailable 2" or "maxUnavailable 25%".
+func (p *PDB) Budget() string {
+	switch {
+	case p.MinAvailable != "":
+		return "minAvailable " + p.MinAvailable
+	case p.MaxUnavailable != "":
+		return "maxUnavailable " + p.MaxUnavailable
+	default:
+		return "no budget"
+	}
+}
+
+// Selects reports whether the budget covers the pods of a workload.
+func (p *PDB) Selects(w *Workload) bool {
+	return p.Selector != nil && p.Selector.Matches(w.TemplateLabels())
+}
+
+// Blocks reports whether the budget allows no voluntary disruption of a
+// workload with the given replicas, so draining its nodes would hang. The
+// controller's count is used when there is one; otherwise the budget is
+// evaluated against the replicas, rounding percentages up like the
+// disruption controller.
+func (p *PDB) Blocks(replicas int32) bool {
+	if p.DisruptionsAllowed != nil {
+		return *p.DisruptionsAllowed == 0
+	}
+
+	switch {
+	case p.MaxUnavailable != "":
+		value := intstr.Parse(p.MaxUnavailable)
+		unavailable, err := intstr.GetScaledValueFromIntOrPercent(&value, int(replicas), true)
+		return err == nil && unavailable <= 0
+	case p.MinAvailable != "":
+		value := intstr.Parse(p.MinAvailable)
+		available, err := intstr.GetScaledValueFromIntOrPercent(&value, int(replicas), true)
+		return err == nil && available >= int(replicas)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReadSnapshot decodes a topology previously exported as JSON (see
+// docs/TOPOLOGY_SCHEMA.md). Documents with a different schema version are
+// rejected. Exported edges are dropped because renderers derive their own.
+func ReadSnapshot(r io.Reader) (*Cluster, error) {
+	var cluster Cluster
+	if err := json.NewDecoder(r).Decode(&cluster); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	if cluster.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported snapshot schema version %q (expected %q)", cluster.SchemaVersion, SchemaVersion)
+	}
+
+	cluster.SchemaVersion = ""
+	cluster.Edges = nil
+	return &cluster, nil
+}
@@ -1,53 +1,507 @@
 package model
 
+import "slices"
+
+// SchemaVersion identifies the layout of serialized topology documents. It
+// changes whenever a field is renamed, removed or changes meaning; adding
+// optional fields keeps the version. See docs/TOPOLOGY_SCHEMA.md.
+const SchemaVersion = "k8s-d2/v1"
+
 type Cluster struct {
-	Name       string
-	Namespaces []Namespace
+	// SchemaVersion is set to the SchemaVersion constant on serialized
+	// topologies and empty on clusters built in memory.
+	SchemaVersion string      `json:"schemaVersion,omitempty"`
+	Name          string      `json:"name"`
+	Namespaces    []Namespace `json:"namespaces"`
+	// Nodes are only collected for the node and zone views.
+	Nodes []Node `json:"nodes,omitempty"`
+	// Edges holds the relationships derived from the namespaces. It is only
+	// populated on export; renderers derive edges themselves.
+	Edges []Edge `json:"edges,omitempty"`
+}
+
+// Namespace returns the namespace of the given name, or nil. The pointer
+// refers into the cluster, so changes through it stick.
+func (c *Cluster) Namespace(name string) *Namespace {
+	for i := range c.Namespaces {
+		if c.Namespaces[i].Name == name {
+			return &c.Namespaces[i]
+		}
+	}
+	return nil
+}
+
+// NamespaceNames returns the names of the cluster's namespaces, in order.
+func (c *Cluster) NamespaceNames() []string {
+	names := make([]string, 0, len(c.Namespaces))
+	for _, ns := range c.Namespaces {
+		names = append(names, ns.Name)
+	}
+	return names
 }
 
 type Namespace struct {
-	Name         string
-	Deployments  []Workload
-	StatefulSets []Workload
-	DaemonSets   []Workload
-	Services     []Service
-	ConfigMaps   int
-	Secrets      int
-	PVCs         []PVC
+	Name         string     `json:"name"`
+	Deployments  []Workload `json:"deployments,omitempty"`
+	StatefulSets []Workload `json:"statefulSets,omitempty"`
+	DaemonSets   []Workload `json:"daemonSets,omitempty"`
+	CronJobs     []Workload `json:"cronJobs,omitempty"`
+	Jobs         []Workload `json:"jobs,omitempty"`
+	Services     []Service  `json:"services,omitempty"`
+	ConfigMaps   int        `json:"configMaps"`
+	Secrets      int        `json:"secrets"`
+	PVCs         []PVC      `json:"pvcs,omitempty"`
+	Ingresses    []Ingress  `json:"ingresses,omitempty"`
+	Gateways     []Gateway  `json:"gateways,omitempty"`
+	Routes       []Route    `json:"routes,omitempty"`
+	// ReferenceGrants allow routes in other namespaces to target services in
+	// this one. They are not drawn; they decide RouteBackend.Denied.
+	ReferenceGrants []ReferenceGrant `json:"referenceGrants,omitempty"`
+	NetworkPolicies []NetworkPolicy  `json:"networkPolicies,omitempty"`
+	PDBs            []PDB            `json:"pdbs,omitempty"`
+	// Labels are only collected with network policies, to evaluate their
+	// namespaceSelectors.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AllWorkloads returns every workload in the namespace, in the order they
+// are rendered: Deployments, StatefulSets, DaemonSets, CronJobs, then Jobs.
+func (ns *Namespace) AllWorkloads() []Workload {
+	var all []Workload
+	for _, workloads := range ns.workloadLists() {
+		all = append(all, workloads...)
+	}
+	return all
+}
+
+// Workload returns the workload of the given kind and name, or nil. The
+// pointer refers into the namespace, so changes through it stick.
+func (ns *Namespace) Workload(kind, name string) *Workload {
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			if workloads[i].Kind == kind && workloads[i].Name == name {
+				return &workloads[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Service returns the service of the given name, or nil.
+func (ns *Namespace) Service(name string) *Service {
+	for i := range ns.Services {
+		if ns.Services[i].Name == name {
+			return &ns.Services[i]
+		}
+	}
+	return nil
+}
+
+// ClearPods drops the ReplicaSets and Pods of every workload.
+func (ns *Namespace) ClearPods() {
+	for _, workloads := range ns.workloadLists() {
+		for i := range workloads {
+			workloads[i].ReplicaSets = nil
+			workloads[i].Pods = nil
+		}
+	}
+}
+
+// workloadLists returns the namespace's workload slices in render order,
+// for callers that update workloads in place.
+func (ns *Namespace) workloadLists() [][]Workload {
+	return [][]Workload{ns.Deployments, ns.StatefulSets, ns.DaemonSets, ns.CronJobs, ns.Jobs}
 }
 
 type Workload struct {
-	Name         string
-	Kind         string // Deployment, StatefulSet, DaemonSet
-	Replicas     int32
-	Labels       map[string]string
-	VolumeMounts []VolumeMount
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // Deployment, StatefulSet, DaemonSet, CronJob, Job
+	Replicas int32  `json:"replicas"`
+	// Labels are the matchLabels of the workload's selector, or the pod
+	// template labels of CronJobs and Jobs. Relationships are derived from
+	// PodLabels; see TemplateLabels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// PodLabels are the labels of the pod template.
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Selector is the full selector of Deployments, StatefulSets and
+	// DaemonSets. Job selectors are generated, so they are not kept.
+	Selector     *LabelSelector `json:"selector,omitempty"`
+	VolumeMounts []VolumeMount  `json:"volumeMounts,omitempty"`
+	// ContainerPorts are the ports of the pod template's containers.
+	ContainerPorts []ContainerPort `json:"containerPorts,omitempty"`
+	// ConfigRefs are only kept when ConfigMap and Secret references are
+	// collected.
+	ConfigRefs []ConfigRef `json:"configRefs,omitempty"`
+	// ServiceCalls are the services environment variables name by cluster
+	// DNS name, in any namespace.
+	ServiceCalls []ServiceCall `json:"serviceCalls,omitempty"`
+	// NetworkAccess is AccessIsolated or AccessOpen when network policies
+	// were evaluated and leave the workload with no traffic or all traffic.
+	NetworkAccess string `json:"networkAccess,omitempty"`
+	// Spread is set on Deployments and StatefulSets whose pod template
+	// spreads replicas with topologySpreadConstraints or pod anti-affinity.
+	Spread bool `json:"spread,omitempty"`
+	// Batch is only set on CronJobs and Jobs.
+	Batch *Batch `json:"batch,omitempty"`
+	// Autoscaler is set when an HPA or KEDA ScaledObject scales the workload.
+	Autoscaler *Autoscaler `json:"autoscaler,omitempty"`
+	// ReplicaSets (Deployments) and Pods (every other kind) are only
+	// collected in pod detail mode.
+	ReplicaSets []ReplicaSet `json:"replicaSets,omitempty"`
+	Pods        []Pod        `json:"pods,omitempty"`
+}
+
+// AllPods returns the pods of a workload, including those of its
+// ReplicaSets.
+func (w *Workload) AllPods() []Pod {
+	pods := slices.Clone(w.Pods)
+	for _, rs := range w.ReplicaSets {
+		pods = append(pods, rs.Pods...)
+	}
+	return pods
+}
+
+// TemplateLabels returns the labels selectors are matched against: the pod
+// template labels, or Labels for snapshots taken before they were recorded.
+func (w *Workload) TemplateLabels() map[string]string {
+	if w.PodLabels != nil {
+		return w.PodLabels
+	}
+	return w.Labels
 }
 
+// ResolvePort returns the container port a service port targets: the
+// one named by its targetPort, or the numeric targetPort itself. It returns
+// zero for names no container of the workload declares.
+func (w *Workload) ResolvePort(p Port) int32 {
+	if p.TargetPortName == "" {
+		return p.TargetPort
+	}
+	for _, cp := range w.ContainerPorts {
+		if cp.Name == p.TargetPortName {
+			return cp.Port
+		}
+	}
+	return 0
+}
+
+// ReplicaSet is a revision of a Deployment and the pods it runs.
+type ReplicaSet struct {
+	Name     string `json:"name"`
+	Replicas int32  `json:"replicas"` // Desired replicas
+	Ready    int32  `json:"ready"`
+	Pods     []Pod  `json:"pods,omitempty"`
+}
+
+// Pod is a running instance of a workload.
+type Pod struct {
+	Name         string        `json:"name"`
+	Phase        string        `json:"phase"` // Pending, Running, Succeeded, Failed, Unknown
+	Restarts     int32         `json:"restarts"`
+	Node         string        `json:"node,omitempty"`
+	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// Batch is the schedule and run state of a CronJob or Job.
+type Batch struct {
+	Schedule string `json:"schedule,omitempty"` // Cron expression; CronJobs only
+	Suspend  bool   `json:"suspend,omitempty"`
+	LastRun  string `json:"lastRun,omitempty"` // One of the Run constants; empty if it never ran
+	Active   int32  `json:"active"`            // Running jobs of a CronJob, running pods of a Job
+	CronJob  string `json:"cronJob,omitempty"` // CronJob that created a Job
+}
+
+// Autoscaler is the HorizontalPodAutoscaler or KEDA ScaledObject scaling a
+// workload.
+type Autoscaler struct {
+	Kind        string `json:"kind"` // HorizontalPodAutoscaler, ScaledObject
+	Name        string `json:"name"`
+	MinReplicas int32  `json:"minReplicas"`
+	MaxReplicas int32  `json:"maxReplicas"`
+	// Metrics describes what drives scaling, e.g. "cpu 45% / 70%" (current
+	// and target) for HPAs or "prometheus trigger" for ScaledObjects.
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+// Batch.LastRun values.
+const (
+	RunRunning   = "Running"
+	RunSucceeded = "Succeeded"
+	RunFailed    = "Failed"
+)
+
 // VolumeMount represents a volume mount in a workload container, capturing
 // the PVC reference and mount metadata (path, read-only status).
 type VolumeMount struct {
-	PVCName   string // Name of the PersistentVolumeClaim
-	MountPath string // Path where volume is mounted (e.g., "/var/log/app")
-	ReadOnly  bool   // Whether volume is mounted read-only
+	PVCName   string `json:"pvcName"`   // Name of the PersistentVolumeClaim
+	MountPath string `json:"mountPath"` // Path where volume is mounted (e.g., "/var/log/app")
+	ReadOnly  bool   `json:"readOnly"`  // Whether volume is mounted read-only
 }
 
 type Service struct {
-	Name     string
-	Type     string // ClusterIP, NodePort, LoadBalancer
-	Selector map[string]string
-	Ports    []Port
+	Name     string            `json:"name"`
+	Type     string            `json:"type"` // ClusterIP, NodePort, LoadBalancer, ExternalName
+	Selector map[string]string `json:"selector,omitempty"`
+	Ports    []Port            `json:"ports,omitempty"`
+	// ExternalName is the DNS name an ExternalName service is an alias for.
+	ExternalName string `json:"externalName,omitempty"`
+	// Headless is set on services with clusterIP None, whose DNS records
+	// point at each pod instead of a virtual IP.
+	Headless bool `json:"headless,omitempty"`
+	// LoadBalancer lists the IPs and hostnames assigned to a LoadBalancer
+	// service. It is empty while the load balancer is being provisioned.
+	LoadBalancer []string `json:"loadBalancer,omitempty"`
+	// Endpoints are only collected from EndpointSlices on request. When
+	// set, they connect the service instead of its selector.
+	Endpoints *Endpoints `json:"endpoints,omitempty"`
 }
 
+// Service.Type values.
+const (
+	ServiceClusterIP    = "ClusterIP"
+	ServiceNodePort     = "NodePort"
+	ServiceLoadBalancer = "LoadBalancer"
+	ServiceExternalName = "ExternalName"
+)
+
 type Port struct {
-	Name       string
-	Port       int32
-	TargetPort int32
+	Name string `json:"name,omitempty"`
+	Port int32  `json:"port"`
+	// TargetPort is the numeric target port, defaulting to Port. It is zero
+	// when the target is a container port name; see TargetPortName.
+	TargetPort     int32  `json:"targetPort"`
+	TargetPortName string `json:"targetPortName,omitempty"`
+	Protocol       string `json:"protocol,omitempty"` // TCP, UDP, SCTP
+	NodePort       int32  `json:"nodePort,omitempty"` // NodePort and LoadBalancer services
+}
+
+// ContainerPort is a port declared by a container of a workload's pod
+// template. Named ones are what service targetPort names resolve to.
+type ContainerPort struct {
+	Name     string `json:"name,omitempty"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol,omitempty"`
 }
 
 type PVC struct {
-	Name         string
-	StorageClass string
-	Capacity     string
-	BoundPod     string
+	Name         string `json:"name"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Capacity     string `json:"capacity,omitempty"`
+	Requested    string `json:"requested,omitempty"` // Size in the spec; Capacity is the bound size once known
+	BoundPod     string `json:"boundPod,omitempty"`
+}
+
+// Node is a machine pods are scheduled on.
+type Node struct {
+	Name   string  `json:"name"`
+	Zone   string  `json:"zone,omitempty"`   // topology.kubernetes.io/zone
+	Region string  `json:"region,omitempty"` // topology.kubernetes.io/region
+	CPU    string  `json:"cpu,omitempty"`    // Capacity, e.g. "4"
+	Memory string  `json:"memory,omitempty"` // Capacity, e.g. "16Gi"
+	Pods   string  `json:"pods,omitempty"`   // Pod capacity, e.g. "110"
+	Taints []Taint `json:"taints,omitempty"`
+}
+
+// Taint keeps pods without a matching toleration off a node.
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"` // NoSchedule, PreferNoSchedule, NoExecute
+}
+
+// Ingress is an HTTP entry point routing hosts and paths to services.
+type Ingress struct {
+	Name           string          `json:"name"`
+	Class          string          `json:"class,omitempty"`
+	Hosts          []string        `json:"hosts,omitempty"`
+	TLSSecrets     []string        `json:"tlsSecrets,omitempty"`
+	Routes         []IngressRoute  `json:"routes,omitempty"`
+	DefaultBackend *IngressBackend `json:"defaultBackend,omitempty"`
+}
+
+// IngressRoute sends requests for a host and path to a backend. An empty
+// host matches every host.
+type IngressRoute struct {
+	Host    string         `json:"host,omitempty"`
+	Path    string         `json:"path,omitempty"`
+	Backend IngressBackend `json:"backend"`
+}
+
+type IngressBackend struct {
+	Service string `json:"service"`
+	Port    string `json:"port,omitempty"` // Port number or name
+}
+
+// Gateway is a Gateway API gateway and its listeners.
+type Gateway struct {
+	Name      string     `json:"name"`
+	Class     string     `json:"class,omitempty"`
+	Listeners []Listener `json:"listeners,omitempty"`
+}
+
+type Listener struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Port     int32  `json:"port"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// Route is a Gateway API HTTPRoute, GRPCRoute or TCPRoute.
+type Route struct {
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"` // HTTPRoute, GRPCRoute, TCPRoute
+	Hostnames  []string       `json:"hostnames,omitempty"`
+	ParentRefs []ParentRef    `json:"parentRefs,omitempty"`
+	Backends   []RouteBackend `json:"backends,omitempty"`
+}
+
+// ParentRef is a gateway a route attaches to, optionally to one listener.
+type ParentRef struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// RouteBackend is a service a route forwards to.
+type RouteBackend struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Port      int32  `json:"port,omitempty"`
+	Weight    int32  `json:"weight"`
+	// Denied marks a cross-namespace backend that no ReferenceGrant allows.
+	Denied bool `json:"denied,omitempty"`
+}
+
+// ReferenceGrant lists which kinds in which namespaces may reference
+// objects in the grant's namespace.
+type ReferenceGrant struct {
+	Name string        `json:"name"`
+	From []GrantFrom   `json:"from"`
+	To   []GrantTarget `json:"to"`
+}
+
+type GrantFrom struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+}
+
+// GrantTarget is a kind, optionally restricted to one name, that a
+// ReferenceGrant exposes.
+type GrantTarget struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+}
+
+// NetworkPolicy is a NetworkPolicy with the pods it selects and the traffic
+// it allows. PolicyTypes is always set, defaulted as the API server does.
+type NetworkPolicy struct {
+	Name        string        `json:"name"`
+	PodSelector LabelSelector `json:"podSelector"`
+	PolicyTypes []string      `json:"policyTypes"` // Ingress, Egress
+	Ingress     []PolicyRule  `json:"ingress,omitempty"`
+	Egress      []PolicyRule  `json:"egress,omitempty"`
+}
+
+// PolicyRule allows traffic from (ingress) or to (egress) any of its peers
+// on any of its ports. No peers means every peer; no ports means every port.
+type PolicyRule struct {
+	Peers []PolicyPeer `json:"peers,omitempty"`
+	Ports []PolicyPort `json:"ports,omitempty"`
+}
+
+// PolicyPeer selects pods, namespaces or an IP range. A peer with only a
+// PodSelector refers to the policy's own namespace.
+type PolicyPeer struct {
+	PodSelector       *LabelSelector `json:"podSelector,omitempty"`
+	NamespaceSelector *LabelSelector `json:"namespaceSelector,omitempty"`
+	IPBlock           *IPBlock       `json:"ipBlock,omitempty"`
+}
+
+type IPBlock struct {
+	CIDR   string   `json:"cidr"`
+	Except []string `json:"except,omitempty"`
+}
+
+type PolicyPort struct {
+	Protocol string `json:"protocol"`
+	Port     string `json:"port,omitempty"` // Port number or name; empty for every port
+	EndPort  int32  `json:"endPort,omitempty"`
+}
+
+// LabelSelector is a Kubernetes label selector. An empty selector matches
+// everything.
+type LabelSelector struct {
+	MatchLabels      map[string]string  `json:"matchLabels,omitempty"`
+	MatchExpressions []LabelRequirement `json:"matchExpressions,omitempty"`
+}
+
+// LabelRequirement is a matchExpressions entry.
+type LabelRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"` // In, NotIn, Exists, DoesNotExist
+	Values   []string `json:"values,omitempty"`
+}
+
+// Resource kinds used in Workload.Kind and Ref.Kind.
+const (
+	KindDeployment    = "Deployment"
+	KindStatefulSet   = "StatefulSet"
+	KindDaemonSet     = "DaemonSet"
+	KindCronJob       = "CronJob"
+	KindJob           = "Job"
+	KindReplicaSet    = "ReplicaSet"
+	KindPod           = "Pod"
+	KindService       = "Service"
+	KindPVC           = "PersistentVolumeClaim"
+	KindIngress       = "Ingress"
+	KindGateway       = "Gateway"
+	KindHTTPRoute     = "HTTPRoute"
+	KindGRPCRoute     = "GRPCRoute"
+	KindTCPRoute      = "TCPRoute"
+	KindNetworkPolicy = "NetworkPolicy"
+	KindIPBlock       = "IPBlock" // Ref.Name is the CIDR
+	KindHPA           = "HorizontalPodAutoscaler"
+	KindScaledObject  = "ScaledObject"
+	KindPDB           = "PodDisruptionBudget"
+	KindConfigMap     = "ConfigMap"
+	KindSecret        = "Secret"
+	KindAddress       = "Address"  // Endpoint without a pod; Ref.Name is the IP
+	KindDNSName       = "DNSName"  // Target of an ExternalName service; Ref.Name is the hostname
+	KindInternet      = "Internet" // Where LoadBalancer traffic comes from
+)
+
+// Ref identifies a resource in the topology by kind, namespace and name.
+type Ref struct {
+	Kind      string `json:"kind"` // Any of the Kind constants
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Edge types describe how two resources are related.
+const (
+	EdgeSelects  = "selects"  // Service selects a workload's pods
+	EdgeMounts   = "mounts"   // Workload mounts a PVC
+	EdgeRoutes   = "routes"   // Ingress or route sends traffic to a service
+	EdgeAttaches = "attaches" // Gateway serves a route attached to it
+	EdgeAllows   = "allows"   // Network policies allow traffic between the two
+	EdgeOwns     = "owns"     // Owner created a resource: CronJob → Job, workload → ReplicaSet → Pod
+	EdgeScales   = "scales"   // Autoscaler scales a workload
+	EdgeUses     = "uses"     // Workload reads a ConfigMap or Secret
+	EdgeServes   = "serves"   // Service has EndpointSlice endpoints on a workload, pod or address
+	EdgeAliases  = "aliases"  // ExternalName service resolves to a DNS name
+	EdgeExposes  = "exposes"  // The internet reaches a LoadBalancer service
+	EdgeResolves = "resolves" // In-cluster DNS name of an ExternalName service resolves to a service
+	EdgeCalls    = "calls"    // Workload environment names a service by DNS name
+)
+
+// Edge is a relationship derived between two resources. Labels may span
+// several lines separated by "\n"; renderers escape them for their format.
+type Edge struct {
+	From  Ref    `json:"from"`
+	To    Ref    `json:"to"`
+	Type  string `json:"type"`
+	Label string `json:"label,omitempty"`
+	// Broken marks a relationship nothing can flow through, such as a
+	// service with no ready endpoints on its target.
+	Broken bool `json:"broken,omitempty"`
 }
@@ -1,16 +1,19 @@
 package render
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
 	"strings"
 
 	"github.com/vieitesss/k8s-d2/pkg/model"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type D2Renderer struct {
 	w           io.Writer
 	gridColumns int
+	view        View
 }
 
 func NewD2Renderer(w io.Writer, gridColumns int) *D2Renderer {
@@ -33,7 +36,11 @@ direction: right
 		return err
 	}
 
-	if err := r.renderLegend(); err != nil {
+	if r.view.NeedsNodes() {
+		return r.renderPlacementView(cluster)
+	}
+
+	if err := r.renderLegend(cluster); err != nil {
 		return err
 	}
 
@@ -57,7 +64,26 @@ direction: right
 		}
 	}
 
-	return nil
+	return r.renderCrossNamespaceEdges(cluster)
+}
+
+// renderCrossNamespaceEdges draws the edges between namespaces at the root
+// of the diagram, where both ends are reachable by their full paths.
+func (r *D2Renderer) renderCrossNamespaceEdges(cluster *model.Cluster) error {
+	container := ""
+	if r.gridColumns > 0 {
+		container = "namespaces"
+	}
+
+	var b strings.Builder
+	for _, e := range CrossNamespaceEdges(cluster) {
+		r.writeEdgePath(&b, &e, "", QualifiedID(e.From, container), QualifiedID(e.To, container))
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	_, err := fmt.Fprint(r.w, b.String())
+	return err
 }
 
 func (r *D2Renderer) renderNamespaceIndented(ns *model.Namespace, indent string) error {
@@ -69,10 +95,14 @@ func (r *D2Renderer) renderNamespaceIndented(ns *model.Namespace, indent string)
 	fmt.Fprintf(&b, "%s  grid-columns: 3\n", indent)
 	fmt.Fprintf(&b, "%s  style.fill: \"#f0f0f0\"\n\n", indent)
 
+	r.writeIngresses(&b, ns, indent)
+	r.writeGatewayAPI(&b, ns, indent)
 	r.writeAllWorkloads(&b, ns, indent)
 	r.writeAllServices(&b, ns, indent)
 	r.writeConfigInfo(&b, ns, indent)
 	r.writePVCs(&b, ns, indent)
+	r.writeIPBlocks(&b, ns, indent)
+	r.writeOrphanPDBs(&b, ns, indent)
 	r.writeConnections(&b, ns, indent)
 
 	b.WriteString(fmt.Sprintf("%s}\n\n", indent))
@@ -84,13 +114,19 @@ func (r *D2Renderer) renderNamespaceIndented(ns *model.Namespace, indent string)
 
 func (r *D2Renderer) writeAllWorkloads(b *strings.Builder, ns *model.Namespace, indent string) {
 	for _, w := range ns.Deployments {
-		r.writeWorkload(b, &w, indent)
+		r.writeWorkload(b, ns, &w, indent)
 	}
 	for _, w := range ns.StatefulSets {
-		r.writeWorkload(b, &w, indent)
+		r.writeWorkload(b, ns, &w, indent)
 	}
 	for _, w := range ns.DaemonSets {
-		r.writeWorkload(b, &w, indent)
+		r.writeWorkload(b, ns, &w, indent)
+	}
+	for _, w := range ns.CronJobs {
+		r.writeWorkload(b, ns, &w, indent)
+	}
+	for _, w := range ns.Jobs {
+		r.writeWorkload(b, ns, &w, indent)
 	}
 }
 
@@ -98,6 +134,26 @@ func (r *D2Renderer) writeAllServices(b *strings.Builder, ns *model.Namespace, i
 	for _, svc := range ns.Services {
 		r.writeService(b, &svc, indent)
 	}
+	for _, name := range missingServices(ns) {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: model.KindService, Name: name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(missingServiceLabelLines(name), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, warnFill)
+		fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, warnStroke)
+		fmt.Fprintf(b, "%s    style.stroke-width: 2\n", indent)
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+	for _, target := range endpointNodes(ns) {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: target.Kind, Name: target.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(endpointLabelLines(&target), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(endpointNodeKind(target.Kind)))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+	for _, ref := range externalNodes(ns) {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(ref))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(externalLabelLines(ref), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeExternal))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
 }
 
 func (r *D2Renderer) writeConfigInfo(b *strings.Builder, ns *model.Namespace, indent string) {
@@ -107,6 +163,18 @@ func (r *D2Renderer) writeConfigInfo(b *strings.Builder, ns *model.Namespace, in
 		fmt.Fprintf(b, "%s    style.fill: \"#ffffcc\"\n", indent)
 		fmt.Fprintf(b, "%s  }\n", indent)
 	}
+	for _, ref := range ns.ConfigObjects() {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: ref.Kind, Name: ref.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(configLabelLines(&ref), "\n")))
+		if ref.Missing {
+			fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, warnFill)
+			fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, warnStroke)
+			fmt.Fprintf(b, "%s    style.stroke-width: 2\n", indent)
+		} else {
+			fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeConfig))
+		}
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
 }
 
 func (r *D2Renderer) writePVCs(b *strings.Builder, ns *model.Namespace, indent string) {
@@ -126,80 +194,152 @@ func (r *D2Renderer) writePVCs(b *strings.Builder, ns *model.Namespace, indent s
 	}
 }
 
-func (r *D2Renderer) writeWorkload(b *strings.Builder, w *model.Workload, indent string) {
+func (r *D2Renderer) writeIPBlocks(b *strings.Builder, ns *model.Namespace, indent string) {
+	for _, block := range ns.IPBlocks() {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: model.KindIPBlock, Name: block.CIDR}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(ipBlockLabelLines(&block), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeIPBlock))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+}
+
+func (r *D2Renderer) writeOrphanPDBs(b *strings.Builder, ns *model.Namespace, indent string) {
+	for _, pdb := range orphanPDBs(ns) {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: model.KindPDB, Name: pdb.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(orphanPDBLabelLines(&pdb), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, warnFill)
+		fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, warnStroke)
+		fmt.Fprintf(b, "%s    style.stroke-width: 2\n", indent)
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+}
+
+func (r *D2Renderer) writeIngresses(b *strings.Builder, ns *model.Namespace, indent string) {
+	for _, ing := range ns.Ingresses {
+		fmt.Fprintf(b, "%s  ing_%s: {\n", indent, SanitizeID(ing.Name))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(ingressLabelLines(&ing), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeIngress))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+}
+
+func (r *D2Renderer) writeGatewayAPI(b *strings.Builder, ns *model.Namespace, indent string) {
+	for _, gw := range ns.Gateways {
+		fmt.Fprintf(b, "%s  gw_%s: {\n", indent, SanitizeID(gw.Name))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(gatewayLabelLines(&gw), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeGateway))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+	for _, route := range ns.Routes {
+		ref := model.Ref{Kind: route.Kind, Namespace: ns.Name, Name: route.Name}
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(ref))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(routeLabelLines(&route, ns.Name), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeRoute))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+}
+
+func (r *D2Renderer) writeWorkload(b *strings.Builder, ns *model.Namespace, w *model.Workload, indent string) {
 	wID := SanitizeID(w.Name)
-	icon := WorkloadIcon(w.Kind)
 
 	fmt.Fprintf(b, "%s  %s: {\n", indent, wID)
-	fmt.Fprintf(b, "%s    label: \"%s %s (%d)\"\n", indent, icon, w.Name, w.Replicas)
+	fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(workloadLabelLines(ns, w), "\n")))
 	fmt.Fprintf(b, "%s  }\n", indent)
+
+	for _, rs := range w.ReplicaSets {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: model.KindReplicaSet, Name: rs.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(replicaSetLabelLines(&rs), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeReplicaSet))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+	for _, pod := range w.AllPods() {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: model.KindPod, Name: pod.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(podLabelLines(&pod), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodePod))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
+	if a := w.Autoscaler; a != nil {
+		fmt.Fprintf(b, "%s  %s: {\n", indent, NodeID(model.Ref{Kind: a.Kind, Name: a.Name}))
+		fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(autoscalerLabelLines(a), "\n")))
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, nodeFill(nodeAutoscaler))
+		fmt.Fprintf(b, "%s  }\n", indent)
+	}
 }
 
 func (r *D2Renderer) writeService(b *strings.Builder, svc *model.Service, indent string) {
 	svcID := SanitizeID(svc.Name)
 
 	fmt.Fprintf(b, "%s  svc_%s: {\n", indent, svcID)
-	fmt.Fprintf(b, "%s    label: \"⎈ %s\\n%s\"\n", indent, svc.Name, svc.Type)
-	fmt.Fprintf(b, "%s    style.fill: \"#cce5ff\"\n", indent)
+	fmt.Fprintf(b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(serviceLabelLines(svc), "\n")))
+	if serviceUnready(svc) {
+		fmt.Fprintf(b, "%s    style.fill: \"%s\"\n", indent, warnFill)
+		fmt.Fprintf(b, "%s    style.stroke: \"%s\"\n", indent, warnStroke)
+		fmt.Fprintf(b, "%s    style.stroke-width: 2\n", indent)
+	} else {
+		fmt.Fprintf(b, "%s    style.fill: \"#cce5ff\"\n", indent)
+	}
 	fmt.Fprintf(b, "%s  }\n", indent)
 }
 
 func (r *D2Renderer) writeConnections(b *strings.Builder, ns *model.Namespace, indent string) {
-	for _, svc := range ns.Services {
-		if len(svc.Selector) == 0 {
-			continue
-		}
-		r.writeServiceConnections(b, &svc, ns, indent)
-	}
-
-	// Only render workload-to-PVC connections if PVCs are actually present
-	if len(ns.PVCs) > 0 {
-		r.writeWorkloadPVCConnections(b, ns, indent)
+	for _, e := range NamespaceEdges(ns) {
+		r.writeEdge(b, &e, indent)
 	}
 }
 
-func (r *D2Renderer) writeWorkloadPVCConnections(b *strings.Builder, ns *model.Namespace, indent string) {
-	workloadGroups := [][]model.Workload{ns.Deployments, ns.StatefulSets, ns.DaemonSets}
-
-	for _, workloads := range workloadGroups {
-		for _, w := range workloads {
-			if len(w.VolumeMounts) == 0 {
-				continue
-			}
-
-			workloadID := SanitizeID(w.Name)
-
-			// Group mounts by PVC (handle case where same PVC mounted at multiple paths)
-			mountsByPVC := make(map[string][]model.VolumeMount)
-			for _, mount := range w.VolumeMounts {
-				mountsByPVC[mount.PVCName] = append(mountsByPVC[mount.PVCName], mount)
-			}
+func (r *D2Renderer) writeEdge(b *strings.Builder, e *model.Edge, indent string) {
+	r.writeEdgePath(b, e, indent+"  ", NodeID(e.From), NodeID(e.To))
+}
 
-			for pvcName, mounts := range mountsByPVC {
-				pvcID := SanitizeID(pvcName)
-				label := model.FormatMountLabel(mounts)
-				fmt.Fprintf(b, "%s  %s -> pvc_%s: \"%s\"\n", indent, workloadID, pvcID, label)
-			}
-		}
+// writeEdgePath writes an edge between the D2 paths of its ends.
+func (r *D2Renderer) writeEdgePath(b *strings.Builder, e *model.Edge, indent, from, to string) {
+	fmt.Fprintf(b, "%s%s -> %s", indent, from, to)
+	if e.Label != "" {
+		fmt.Fprintf(b, ": \"%s\"", EscapeD2(e.Label))
 	}
+	// Allowed traffic is dashed to set it apart from configured relationships
+	switch {
+	case e.Type == model.EdgeAllows:
+		b.WriteString(" {style.stroke-dash: 3}")
+	case e.Broken:
+		fmt.Fprintf(b, " {style.stroke: \"%s\"; style.stroke-width: 2}", brokenStroke)
+	}
+	b.WriteString("\n")
 }
 
-func (r *D2Renderer) writeServiceConnections(b *strings.Builder, svc *model.Service, ns *model.Namespace, indent string) {
-	svcID := SanitizeID(svc.Name)
-	allWorkloads := [][]model.Workload{ns.Deployments, ns.StatefulSets, ns.DaemonSets}
+// legendLabels are the labels of the node kinds listed in the legend.
+var legendLabels = map[nodeKind]string{
+	nodeIngress:     "🌐 Ingress",
+	nodeGateway:     "🚪 Gateway",
+	nodeRoute:       "↪ Route",
+	nodeDeployment:  "● Deployment",
+	nodeStatefulSet: "◉ StatefulSet",
+	nodeDaemonSet:   "◈ DaemonSet",
+	nodeCronJob:     "◷ CronJob",
+	nodeJob:         "▣ Job",
+	nodeReplicaSet:  "▤ ReplicaSet",
+	nodePod:         "▢ Pod",
+	nodeAutoscaler:  "⇅ Autoscaler",
+	nodeService:     "⎈ Service",
+	nodeConfig:      "ConfigMaps | Secrets",
+	nodePVC:         "💾 PVC",
+	nodeIPBlock:     "🌍 IP block",
+	nodeAddress:     "🔌 Address",
+	nodeExternal:    "☁ External",
+}
 
-	for _, workloads := range allWorkloads {
-		for _, w := range workloads {
-			if LabelsMatch(svc.Selector, w.Labels) {
-				wID := SanitizeID(w.Name)
-				fmt.Fprintf(b, "%s  svc_%s -> %s\n", indent, svcID, wID)
-			}
+// renderLegend lists the node kinds drawn for the cluster, so layers that
+// are off or empty do not clutter the legend.
+func (r *D2Renderer) renderLegend(cluster *model.Cluster) error {
+	present := map[nodeKind]bool{}
+	for _, group := range buildGraph(cluster).groups {
+		for _, n := range group.nodes {
+			present[n.kind] = true
 		}
 	}
-}
 
-func (r *D2Renderer) renderLegend() error {
-	legend := `
+	var b strings.Builder
+	b.WriteString(`
 legend: {
   label: "LEGEND"
   grid-rows: 1
@@ -208,50 +348,75 @@ legend: {
   style.stroke-width: 3
   style.font-size: 16
   style.bold: true
+`)
+	for _, kind := range nodeKinds {
+		label, ok := legendLabels[kind]
+		if !ok || !present[kind] {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  %s: {\n    label: \"%s\"\n    style.fill: \"%s\"\n  }\n", kind, label, nodeFill(kind))
+	}
+	b.WriteString("}\n")
 
-  deployment: {
-    label: "● Deployment"
-    style.fill: "#f9f9f9"
-  }
-
-  statefulset: {
-    label: "◉ StatefulSet"
-    style.fill: "#f9f9f9"
-  }
-
-  daemonset: {
-    label: "◈ DaemonSet"
-    style.fill: "#f9f9f9"
-  }
-
-  service: {
-    label: "⎈ Service"
-    style.fill: "#cce5ff"
-  }
-
-  config: {
-    label: "ConfigMaps | Secrets"
-    style.fill: "#ffffcc"
-  }
-
-  pvc: {
-    label: "💾 PVC"
-    style.fill: "#e6f3ff"
-  }
+	_, err := fmt.Fprint(r.w, b.String())
+	return err
 }
-`
-	if _, err := fmt.Fprint(r.w, legend); err != nil {
-		return err
+
+// QualifiedID returns the D2 path of a resource from the root of the
+// diagram, e.g. "namespaces.shop.svc_api", through its namespace container
+// and, when not empty, the container holding the namespaces.
+func QualifiedID(ref model.Ref, container string) string {
+	id := SanitizeID(ref.Namespace) + "." + NodeID(ref)
+	if container == "" {
+		return id
 	}
-	return nil
+	return container + "." + id
 }
 
 // SanitizeID converts a Kubernetes resource name to a valid D2 identifier.
-// D2 syntax doesn't allow hyphens, so we convert them to underscores.
+// D2 syntax doesn't allow hyphens and dots would be D2 paths, so a hyphen
+// becomes one underscore and a dot two. Any other run of characters, such
+// as "--", becomes three underscores, its hex bytes and an underscore, so
+// no two names share an identifier.
 func SanitizeID(s string) string {
-	return strings.ReplaceAll(s, "-", "_")
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && !isIDByte(s[j]) {
+			j++
+		}
+		if j == i {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		switch run := s[i:j]; run {
+		case "-":
+			b.WriteString("_")
+		case ".":
+			b.WriteString("__")
+		default:
+			b.WriteString("___" + hex.EncodeToString([]byte(run)) + "_")
+		}
+		i = j
+	}
+	return b.String()
 }
 
+// isIDByte reports whether c is kept as is in a D2 identifier.
+func isIDByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// EscapeD2 escapes a label for use inside a double-quoted D2 string.
+// Literal backslashes are escaped too, in the same pass, so they cannot
+// combine with the next character into an escape sequence.
+func EscapeD2(s string) string {
+	return d2Escaper.Replace(s)
+}
+
+var d2Escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
 // WorkloadIcon returns the D2 icon for a workload type.
 func WorkloadIcon(kind string) string {
 	switch kind {
@@ -259,22 +424,21 @@ func WorkloadIcon(kind string) string {
 		return "◉"
 	case "DaemonSet":
 		return "◈"
+	case "CronJob":
+		return "◷"
+	case "Job":
+		return "▣"
 	default:
 		return "●"
 	}
 }
 
-// LabelsMatch checks if a selector matches a set of labels.
-// All selector key-value pairs must match the labels for this to return true.
-func LabelsMatch(selector, labels map[string]string) bool {
+// LabelsMatch checks if a service selector matches a set of pod labels, with
+// Kubernetes semantics. An empty selector matches nothing, as services
+// without a selector do not select pods.
+func LabelsMatch(selector, set map[string]string) bool {
 	if len(selector) == 0 {
 		return false
 	}
-
-	for key, value := range selector {
-		if labels[key] != value {
-			return false
-		}
-	}
-	return true
+	return labels.SelectorFromSet(selector).Matches(labels.Set(set))
 }
@@ -0,0 +1,128 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// DOTRenderer renders a cluster as a Graphviz DOT digraph with one cluster
+// subgraph per namespace.
+type DOTRenderer struct {
+	w    io.Writer
+	view View
+}
+
+func NewDOTRenderer(w io.Writer) *DOTRenderer {
+	return &DOTRenderer{w: w}
+}
+
+func (r *DOTRenderer) Render(cluster *model.Cluster) error {
+	if cluster == nil {
+		return fmt.Errorf("cluster is nil, cannot render")
+	}
+
+	g := buildViewGraph(cluster, r.view)
+	var b strings.Builder
+
+	b.WriteString("// Generated by k8s-d2\n")
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(cluster.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	for _, group := range g.groups {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote("cluster_"+group.id))
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(group.label))
+		fmt.Fprintf(&b, "    style=filled;\n")
+		fmt.Fprintf(&b, "    fillcolor=%s;\n", dotQuote(namespaceFill))
+		for _, n := range group.nodes {
+			attrs := []string{
+				"label=" + dotQuote(strings.Join(n.lines, "\n")),
+				"shape=" + dotShape(n.kind),
+				"fillcolor=" + dotQuote(n.fill()),
+			}
+			if n.warn {
+				attrs = append(attrs, "color="+dotQuote(warnStroke), "penwidth=2")
+			}
+			fmt.Fprintf(&b, "    %s [%s];\n", dotQuote(n.id), strings.Join(attrs, ", "))
+		}
+		b.WriteString("  }\n\n")
+	}
+
+	for _, e := range g.edges {
+		var attrs []string
+		if len(e.lines) > 0 {
+			attrs = append(attrs, "label="+dotQuote(strings.Join(e.lines, "\n")))
+		}
+		if e.dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.broken {
+			attrs = append(attrs, "color="+dotQuote(brokenStroke), "penwidth=2")
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.from), dotQuote(e.to))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.from), dotQuote(e.to), strings.Join(attrs, ", "))
+	}
+
+	b.WriteString("}\n")
+
+	if _, err := fmt.Fprint(r.w, b.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dotShape returns the Graphviz shape for a node kind.
+func dotShape(kind nodeKind) string {
+	switch kind {
+	case nodeIngress:
+		return "invhouse"
+	case nodeGateway:
+		return "trapezium"
+	case nodeRoute:
+		return "cds"
+	case nodeStatefulSet:
+		return "box3d"
+	case nodeDaemonSet:
+		return "component"
+	case nodeCronJob:
+		return "tab"
+	case nodeJob:
+		return "folder"
+	case nodePod:
+		return "oval"
+	case nodeAutoscaler:
+		return "hexagon"
+	case nodeHost:
+		return "note"
+	case nodeService:
+		return "ellipse"
+	case nodeConfig:
+		return "note"
+	case nodePVC:
+		return "cylinder"
+	case nodeIPBlock:
+		return "octagon"
+	case nodeAddress:
+		return "circle"
+	case nodeExternal:
+		return "egg"
+	case nodePDB:
+		return "doubleoctagon"
+	default:
+		return "box"
+	}
+}
+
+// dotQuote returns s as a quoted DOT ID, escaping quotes and newlines.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
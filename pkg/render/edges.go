@@ -0,0 +1,632 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// NamespaceEdges derives the relationships between resources of a namespace:
+// ingress → service edges from routing rules, gateway → route → service
+// edges from Gateway API references, internet → LoadBalancer service and
+// ExternalName service → DNS name edges, service → workload edges from
+// label selectors or EndpointSlices (to StatefulSet pods for headless
+// services), CronJob → Job and workload → ReplicaSet → Pod edges from owner
+// references, autoscaler → workload edges, workload → ConfigMap/Secret
+// edges from pod templates, workload → service edges from DNS names in
+// environment variables, workload (or pod) → PVC edges from volume mounts,
+// and the traffic allowed by network policies. Every renderer draws this
+// same edge set, plus the CrossNamespaceEdges of the cluster.
+func NamespaceEdges(ns *model.Namespace) []model.Edge {
+	edges := ingressEdges(ns)
+	edges = append(edges, gatewayEdges(ns)...)
+	edges = append(edges, externalEdges(ns)...)
+	edges = append(edges, serviceEdges(ns)...)
+	edges = append(edges, cronJobEdges(ns)...)
+	edges = append(edges, podEdges(ns)...)
+	edges = append(edges, autoscalerEdges(ns)...)
+	edges = append(edges, configEdges(ns)...)
+	edges = append(edges, callEdges(ns)...)
+
+	// Only derive workload-to-PVC edges if PVCs are actually present
+	if len(ns.PVCs) > 0 {
+		edges = append(edges, pvcEdges(ns)...)
+	}
+
+	if len(ns.NetworkPolicies) > 0 {
+		edges = append(edges, policyEdges(ns)...)
+	}
+
+	return edges
+}
+
+// ingressEdges draws one edge per ingress and backend service, labelled
+// with every host/path routed to it. Backends that do not exist in the
+// namespace still get a broken edge, to a node of missingServices, so
+// broken routes stand out.
+func ingressEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, ing := range ns.Ingresses {
+		var services []string
+		routesByService := make(map[string][]string)
+		addRoute := func(service, route string) {
+			if _, ok := routesByService[service]; !ok {
+				services = append(services, service)
+			}
+			routesByService[service] = append(routesByService[service], route)
+		}
+
+		for _, route := range ing.Routes {
+			addRoute(route.Backend.Service, model.FormatRoute(route))
+		}
+		if ing.DefaultBackend != nil {
+			addRoute(ing.DefaultBackend.Service, "default")
+		}
+
+		for _, service := range services {
+			edges = append(edges, model.Edge{
+				From:   model.Ref{Kind: model.KindIngress, Namespace: ns.Name, Name: ing.Name},
+				To:     model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: service},
+				Type:   model.EdgeRoutes,
+				Label:  strings.Join(routesByService[service], "\n"),
+				Broken: ns.Service(service) == nil,
+			})
+		}
+	}
+
+	return edges
+}
+
+// missingServices returns the services ingresses route to that do not exist
+// in the namespace, which have no node of their own otherwise. Each is
+// returned once, in order of first appearance.
+func missingServices(ns *model.Namespace) []string {
+	var missing []string
+	add := func(service string) {
+		if ns.Service(service) == nil && !slices.Contains(missing, service) {
+			missing = append(missing, service)
+		}
+	}
+	for _, ing := range ns.Ingresses {
+		for _, route := range ing.Routes {
+			add(route.Backend.Service)
+		}
+		if ing.DefaultBackend != nil {
+			add(ing.DefaultBackend.Service)
+		}
+	}
+	return missing
+}
+
+// gatewayEdges draws gateway → route edges for each parentRef, labelled with
+// the listener the route attaches to, and route → service edges for each
+// backend, labelled with weights when traffic is split. References to other
+// namespaces are drawn by CrossNamespaceEdges.
+func gatewayEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+	for _, route := range ns.Routes {
+		edges = append(edges, routeEdges(ns.Name, &route, func(namespace string) bool { return namespace == ns.Name })...)
+	}
+	return edges
+}
+
+// routeEdges draws the edges of a route in namespace to the gateways and
+// backend services of the namespaces keep accepts. Backends no
+// ReferenceGrant allows are broken.
+func routeEdges(namespace string, route *model.Route, keep func(namespace string) bool) []model.Edge {
+	var edges []model.Edge
+	routeRef := model.Ref{Kind: route.Kind, Namespace: namespace, Name: route.Name}
+
+	for _, parent := range route.ParentRefs {
+		if !keep(parent.Namespace) {
+			continue
+		}
+		edges = append(edges, model.Edge{
+			From:  model.Ref{Kind: model.KindGateway, Namespace: parent.Namespace, Name: parent.Name},
+			To:    routeRef,
+			Type:  model.EdgeAttaches,
+			Label: parent.SectionName,
+		})
+	}
+
+	weighted := len(route.Backends) > 1
+	var services []model.Ref
+	weightsByService := make(map[model.Ref][]string)
+	denied := make(map[model.Ref]bool)
+	for _, backend := range route.Backends {
+		weighted = weighted || backend.Weight != 1
+		if !keep(backend.Namespace) {
+			continue
+		}
+		service := model.Ref{Kind: model.KindService, Namespace: backend.Namespace, Name: backend.Service}
+		if _, ok := weightsByService[service]; !ok {
+			services = append(services, service)
+		}
+		weightsByService[service] = append(weightsByService[service], fmt.Sprintf("weight %d", backend.Weight))
+		denied[service] = denied[service] || backend.Denied
+	}
+
+	for _, service := range services {
+		edge := model.Edge{
+			From:   routeRef,
+			To:     service,
+			Type:   model.EdgeRoutes,
+			Broken: denied[service],
+		}
+		if weighted {
+			edge.Label = strings.Join(weightsByService[service], "\n")
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges
+}
+
+// externalEdges links the internet to every LoadBalancer service and each
+// ExternalName service to the DNS name it aliases, and that name to the
+// service it resolves to when it is in the namespace.
+func externalEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, svc := range ns.Services {
+		svcRef := model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: svc.Name}
+		if svc.ExternalName != "" {
+			dnsRef := model.Ref{Kind: model.KindDNSName, Namespace: ns.Name, Name: svc.ExternalName}
+			edges = append(edges, model.Edge{
+				From: svcRef,
+				To:   dnsRef,
+				Type: model.EdgeAliases,
+			})
+			if target, ok := model.ParseServiceDNS(svc.ExternalName); ok && target.Namespace == ns.Name && ns.Service(target.Name) != nil {
+				edges = append(edges, model.Edge{From: dnsRef, To: target, Type: model.EdgeResolves})
+			}
+		}
+		if svc.Type == model.ServiceLoadBalancer {
+			edges = append(edges, model.Edge{
+				From: internetRef(ns.Name),
+				To:   svcRef,
+				Type: model.EdgeExposes,
+			})
+		}
+	}
+
+	return edges
+}
+
+// externalNodes returns the nodes outside the cluster that services lead
+// to: the internet, when a LoadBalancer service is exposed to it, and the
+// DNS names of ExternalName services, each once.
+func externalNodes(ns *model.Namespace) []model.Ref {
+	var refs []model.Ref
+	add := func(ref model.Ref) {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	for _, svc := range ns.Services {
+		if svc.Type == model.ServiceLoadBalancer {
+			add(internetRef(ns.Name))
+		}
+	}
+	for _, svc := range ns.Services {
+		if svc.ExternalName != "" {
+			add(model.Ref{Kind: model.KindDNSName, Namespace: ns.Name, Name: svc.ExternalName})
+		}
+	}
+	return refs
+}
+
+// internetRef is the internet node of a namespace.
+func internetRef(namespace string) model.Ref {
+	return model.Ref{Kind: model.KindInternet, Namespace: namespace, Name: "internet"}
+}
+
+// serviceEdges connects services to the workloads their selectors match,
+// or, when EndpointSlices were read, to the targets of their endpoints.
+// Edges are labelled with the service's ports, one per line, with named
+// target ports resolved against the workload's containers.
+func serviceEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+	workloads := ns.AllWorkloads()
+
+	for _, svc := range ns.Services {
+		if svc.Endpoints != nil {
+			edges = append(edges, endpointEdges(ns, &svc)...)
+			continue
+		}
+		for _, w := range workloads {
+			if !LabelsMatch(svc.Selector, w.TemplateLabels()) {
+				continue
+			}
+			if svc.Headless && w.Kind == model.KindStatefulSet {
+				edges = append(edges, ordinalEdges(ns.Name, &svc, &w)...)
+				continue
+			}
+			edges = append(edges, model.Edge{
+				From:  model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: svc.Name},
+				To:    model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name},
+				Type:  model.EdgeSelects,
+				Label: model.FormatPorts(svc.Ports, &w),
+			})
+		}
+	}
+
+	return edges
+}
+
+// ordinalEdges connects a headless service to each pod of a StatefulSet,
+// since its DNS records let clients reach every ordinal on its own. When
+// pods were not collected, a single edge to the StatefulSet names the
+// ordinals, as "db-{0..2}", above the ports.
+func ordinalEdges(namespace string, svc *model.Service, w *model.Workload) []model.Edge {
+	from := model.Ref{Kind: model.KindService, Namespace: namespace, Name: svc.Name}
+	ports := model.FormatPorts(svc.Ports, w)
+
+	if len(w.Pods) == 0 {
+		var lines []string
+		for _, line := range []string{model.FormatOrdinals(w), ports} {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return []model.Edge{{
+			From:  from,
+			To:    model.Ref{Kind: w.Kind, Namespace: namespace, Name: w.Name},
+			Type:  model.EdgeSelects,
+			Label: strings.Join(lines, "\n"),
+		}}
+	}
+
+	edges := make([]model.Edge, 0, len(w.Pods))
+	for _, pod := range w.Pods {
+		edges = append(edges, model.Edge{
+			From:  from,
+			To:    model.Ref{Kind: model.KindPod, Namespace: namespace, Name: pod.Name},
+			Type:  model.EdgeSelects,
+			Label: ports,
+		})
+	}
+	return edges
+}
+
+// endpointEdges links a service to every target of its endpoints, labelled
+// with their readiness and the service's ports. Targets with no ready
+// endpoint are broken.
+func endpointEdges(ns *model.Namespace, svc *model.Service) []model.Edge {
+	edges := make([]model.Edge, 0, len(svc.Endpoints.Targets))
+	for _, target := range svc.Endpoints.Targets {
+		label := model.FormatReady(target.Ready, target.Total)
+		if len(svc.Ports) > 0 {
+			label += "\n" + model.FormatPorts(svc.Ports, ns.Workload(target.Kind, target.Name))
+		}
+		edges = append(edges, model.Edge{
+			From:   model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: svc.Name},
+			To:     model.Ref{Kind: target.Kind, Namespace: ns.Name, Name: target.Name},
+			Type:   model.EdgeServes,
+			Label:  label,
+			Broken: target.Ready == 0,
+		})
+	}
+	return edges
+}
+
+// endpointNodes returns the endpoint targets that are not workloads, which
+// have no node of their own otherwise: pods without a workload and bare
+// addresses. Each is returned once, in order of first appearance.
+func endpointNodes(ns *model.Namespace) []model.EndpointTarget {
+	var targets []model.EndpointTarget
+	for _, svc := range ns.Services {
+		if svc.Endpoints == nil {
+			continue
+		}
+		for _, target := range svc.Endpoints.Targets {
+			if target.Kind != model.KindPod && target.Kind != model.KindAddress {
+				continue
+			}
+			if !slices.ContainsFunc(targets, func(t model.EndpointTarget) bool { return t.Kind == target.Kind && t.Name == target.Name }) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// cronJobEdges links each CronJob to the Jobs it created that are still
+// around.
+func cronJobEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, cj := range ns.CronJobs {
+		for _, job := range ns.Jobs {
+			if job.Batch == nil || job.Batch.CronJob != cj.Name {
+				continue
+			}
+			edges = append(edges, model.Edge{
+				From: model.Ref{Kind: model.KindCronJob, Namespace: ns.Name, Name: cj.Name},
+				To:   model.Ref{Kind: model.KindJob, Namespace: ns.Name, Name: job.Name},
+				Type: model.EdgeOwns,
+			})
+		}
+	}
+
+	return edges
+}
+
+// podEdges links each workload to its ReplicaSets and pods, and each
+// ReplicaSet to its pods.
+func podEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+	owns := func(from, to model.Ref) {
+		edges = append(edges, model.Edge{From: from, To: to, Type: model.EdgeOwns})
+	}
+
+	for _, w := range ns.AllWorkloads() {
+		workloadRef := model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name}
+		for _, rs := range w.ReplicaSets {
+			rsRef := model.Ref{Kind: model.KindReplicaSet, Namespace: ns.Name, Name: rs.Name}
+			owns(workloadRef, rsRef)
+			for _, pod := range rs.Pods {
+				owns(rsRef, model.Ref{Kind: model.KindPod, Namespace: ns.Name, Name: pod.Name})
+			}
+		}
+		for _, pod := range w.Pods {
+			owns(workloadRef, model.Ref{Kind: model.KindPod, Namespace: ns.Name, Name: pod.Name})
+		}
+	}
+
+	return edges
+}
+
+// autoscalerEdges links each autoscaler to the workload it scales.
+func autoscalerEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, w := range ns.AllWorkloads() {
+		if w.Autoscaler == nil {
+			continue
+		}
+		edges = append(edges, model.Edge{
+			From: model.Ref{Kind: w.Autoscaler.Kind, Namespace: ns.Name, Name: w.Autoscaler.Name},
+			To:   model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name},
+			Type: model.EdgeScales,
+		})
+	}
+
+	return edges
+}
+
+// configEdges links each workload to the ConfigMaps and Secrets it uses,
+// labelled with how it uses them, e.g. "envFrom, volume".
+func configEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, w := range ns.AllWorkloads() {
+		for _, ref := range w.ConfigRefs {
+			edges = append(edges, model.Edge{
+				From:  model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name},
+				To:    model.Ref{Kind: ref.Kind, Namespace: ns.Name, Name: ref.Name},
+				Type:  model.EdgeUses,
+				Label: strings.Join(ref.Via, ", "),
+			})
+		}
+	}
+
+	return edges
+}
+
+// callEdges links each workload to the services of the namespace its
+// environment names by DNS name, labelled with the variables naming them.
+func callEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, w := range ns.AllWorkloads() {
+		for _, call := range w.ServiceCalls {
+			if call.Namespace == ns.Name && ns.Service(call.Service) != nil {
+				edges = append(edges, callEdge(ns.Name, &w, &call))
+			}
+		}
+	}
+
+	return edges
+}
+
+func callEdge(namespace string, w *model.Workload, call *model.ServiceCall) model.Edge {
+	return model.Edge{
+		From:  model.Ref{Kind: w.Kind, Namespace: namespace, Name: w.Name},
+		To:    model.Ref{Kind: model.KindService, Namespace: call.Namespace, Name: call.Service},
+		Type:  model.EdgeCalls,
+		Label: strings.Join(call.Env, ", "),
+	}
+}
+
+// pvcEdges draws mounts from the workload, or from each of its pods when
+// pods were collected, so StatefulSet ordinals point at their own PVCs.
+func pvcEdges(ns *model.Namespace) []model.Edge {
+	var edges []model.Edge
+
+	for _, w := range ns.AllWorkloads() {
+		pods := w.AllPods()
+		if len(pods) == 0 {
+			edges = append(edges, mountEdges(model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name}, w.VolumeMounts)...)
+			continue
+		}
+		for _, pod := range pods {
+			edges = append(edges, mountEdges(model.Ref{Kind: model.KindPod, Namespace: ns.Name, Name: pod.Name}, pod.VolumeMounts)...)
+		}
+	}
+
+	return edges
+}
+
+func mountEdges(from model.Ref, mounts []model.VolumeMount) []model.Edge {
+	var edges []model.Edge
+
+	// Group mounts by PVC (handle case where same PVC mounted at multiple paths),
+	// keeping the order in which PVCs are first mounted
+	var pvcNames []string
+	mountsByPVC := make(map[string][]string)
+	for _, mount := range mounts {
+		if _, ok := mountsByPVC[mount.PVCName]; !ok {
+			pvcNames = append(pvcNames, mount.PVCName)
+		}
+		mountsByPVC[mount.PVCName] = append(mountsByPVC[mount.PVCName], model.FormatMount(mount))
+	}
+
+	for _, pvcName := range pvcNames {
+		edges = append(edges, model.Edge{
+			From:  from,
+			To:    model.Ref{Kind: model.KindPVC, Namespace: from.Namespace, Name: pvcName},
+			Type:  model.EdgeMounts,
+			Label: strings.Join(mountsByPVC[pvcName], "\n"),
+		})
+	}
+
+	return edges
+}
+
+// policyEdges draws the flows the namespace's network policies allow.
+func policyEdges(ns *model.Namespace) []model.Edge {
+	return flowEdges(ns.PolicyFlows())
+}
+
+// flowEdges draws one edge per allowed flow, labelled with the allowed
+// ports. Flows between the same resources from several rules are merged.
+func flowEdges(flows []model.PolicyFlow) []model.Edge {
+	type pair struct{ from, to model.Ref }
+	var pairs []pair
+	ports := make(map[pair][]string)
+	allPorts := make(map[pair]bool)
+
+	for _, f := range flows {
+		p := pair{f.From, f.To}
+		if _, ok := ports[p]; !ok && !allPorts[p] {
+			pairs = append(pairs, p)
+		}
+		if len(f.Ports) == 0 {
+			allPorts[p] = true
+			ports[p] = nil
+			continue
+		}
+		if !allPorts[p] {
+			for _, port := range f.Ports {
+				if !slices.Contains(ports[p], port) {
+					ports[p] = append(ports[p], port)
+				}
+			}
+		}
+	}
+
+	edges := make([]model.Edge, 0, len(pairs))
+	for _, p := range pairs {
+		edges = append(edges, model.Edge{
+			From:  p.from,
+			To:    p.to,
+			Type:  model.EdgeAllows,
+			Label: strings.Join(ports[p], "\n"),
+		})
+	}
+	return edges
+}
+
+// NodeID returns the identifier of a resource within its namespace
+// container. Every kind but workloads is prefixed, so resources cannot
+// collide with workloads of the same name.
+func NodeID(ref model.Ref) string {
+	switch ref.Kind {
+	case model.KindGateway:
+		return "gw_" + SanitizeID(ref.Name)
+	case model.KindHTTPRoute, model.KindGRPCRoute, model.KindTCPRoute:
+		return strings.ToLower(ref.Kind) + "_" + SanitizeID(ref.Name)
+	case model.KindService:
+		return "svc_" + SanitizeID(ref.Name)
+	case model.KindPVC:
+		return "pvc_" + SanitizeID(ref.Name)
+	case model.KindIngress:
+		return "ing_" + SanitizeID(ref.Name)
+	case model.KindReplicaSet:
+		return "rs_" + SanitizeID(ref.Name)
+	case model.KindPod:
+		return "pod_" + SanitizeID(ref.Name)
+	case model.KindHPA:
+		return "hpa_" + SanitizeID(ref.Name)
+	case model.KindScaledObject:
+		return "so_" + SanitizeID(ref.Name)
+	case model.KindPDB:
+		return "pdb_" + SanitizeID(ref.Name)
+	case model.KindConfigMap:
+		return "cm_" + SanitizeID(ref.Name)
+	case model.KindSecret:
+		return "secret_" + SanitizeID(ref.Name)
+	case model.KindIPBlock:
+		return "cidr_" + cidrReplacer.Replace(ref.Name)
+	case model.KindAddress:
+		return "addr_" + cidrReplacer.Replace(ref.Name)
+	case model.KindDNSName:
+		return "dns_" + SanitizeID(ref.Name)
+	case model.KindInternet:
+		return "inet_" + SanitizeID(ref.Name)
+	default:
+		return SanitizeID(ref.Name)
+	}
+}
+
+// cidrReplacer turns a CIDR into an identifier; dots would be D2 paths.
+var cidrReplacer = strings.NewReplacer(".", "_", "/", "_", ":", "_")
+
+// ClusterEdges derives the edges of every namespace in the cluster,
+// followed by those crossing namespaces.
+func ClusterEdges(cluster *model.Cluster) []model.Edge {
+	var edges []model.Edge
+	for _, ns := range cluster.Namespaces {
+		edges = append(edges, NamespaceEdges(&ns)...)
+	}
+	return append(edges, CrossNamespaceEdges(cluster)...)
+}
+
+// CrossNamespaceEdges derives the relationships between resources of
+// different namespaces, which no single namespace can resolve: gateway →
+// route and route → service edges of routes referencing other namespaces
+// (broken when no ReferenceGrant allows the backend), DNS name → service
+// edges of ExternalName services aliasing another namespace's service,
+// workload → service edges from DNS names in environment variables, and
+// the traffic network policies allow through namespaceSelectors. Only
+// namespaces in the cluster are connected.
+func CrossNamespaceEdges(cluster *model.Cluster) []model.Edge {
+	var edges []model.Edge
+
+	for _, ns := range cluster.Namespaces {
+		other := func(namespace string) bool {
+			return namespace != ns.Name && cluster.Namespace(namespace) != nil
+		}
+		hasService := func(namespace, name string) bool {
+			return other(namespace) && cluster.Namespace(namespace).Service(name) != nil
+		}
+
+		for _, route := range ns.Routes {
+			edges = append(edges, routeEdges(ns.Name, &route, other)...)
+		}
+
+		for _, svc := range ns.Services {
+			if target, ok := model.ParseServiceDNS(svc.ExternalName); ok && hasService(target.Namespace, target.Name) {
+				edges = append(edges, model.Edge{
+					From: model.Ref{Kind: model.KindDNSName, Namespace: ns.Name, Name: svc.ExternalName},
+					To:   target,
+					Type: model.EdgeResolves,
+				})
+			}
+		}
+
+		for _, w := range ns.AllWorkloads() {
+			for _, call := range w.ServiceCalls {
+				if hasService(call.Namespace, call.Service) {
+					edges = append(edges, callEdge(ns.Name, &w, &call))
+				}
+			}
+		}
+	}
+
+	return append(edges, flowEdges(cluster.CrossNamespaceFlows())...)
+}
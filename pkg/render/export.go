@@ -0,0 +1,67 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// JSONRenderer serializes a cluster, with its derived edges, as a versioned
+// JSON document for tooling that post-processes the topology.
+type JSONRenderer struct {
+	w io.Writer
+}
+
+func NewJSONRenderer(w io.Writer) *JSONRenderer {
+	return &JSONRenderer{w: w}
+}
+
+func (r *JSONRenderer) Render(cluster *model.Cluster) error {
+	if cluster == nil {
+		return fmt.Errorf("cluster is nil, cannot render")
+	}
+
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exportCluster(cluster))
+}
+
+// YAMLRenderer serializes a cluster like JSONRenderer, as YAML.
+type YAMLRenderer struct {
+	w io.Writer
+}
+
+func NewYAMLRenderer(w io.Writer) *YAMLRenderer {
+	return &YAMLRenderer{w: w}
+}
+
+func (r *YAMLRenderer) Render(cluster *model.Cluster) error {
+	if cluster == nil {
+		return fmt.Errorf("cluster is nil, cannot render")
+	}
+
+	data, err := yaml.Marshal(exportCluster(cluster))
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.w.Write(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exportCluster returns a copy of the cluster stamped with the schema
+// version and carrying its derived edges.
+func exportCluster(cluster *model.Cluster) *model.Cluster {
+	exported := *cluster
+	exported.SchemaVersion = model.SchemaVersion
+	exported.Edges = ClusterEdges(cluster)
+	if exported.Namespaces == nil {
+		exported.Namespaces = []model.Namespace{}
+	}
+	return &exported
+}
@@ -0,0 +1,562 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// nodeKind selects how a node is styled by the graph-based renderers.
+type nodeKind string
+
+const (
+	nodeIngress     nodeKind = "ingress"
+	nodeGateway     nodeKind = "gateway"
+	nodeRoute       nodeKind = "route"
+	nodeDeployment  nodeKind = "deployment"
+	nodeStatefulSet nodeKind = "statefulset"
+	nodeDaemonSet   nodeKind = "daemonset"
+	nodeCronJob     nodeKind = "cronjob"
+	nodeJob         nodeKind = "job"
+	nodeReplicaSet  nodeKind = "replicaset"
+	nodePod         nodeKind = "pod"
+	nodeAutoscaler  nodeKind = "autoscaler"
+	nodeHost        nodeKind = "host" // Node card of the node view
+	nodeService     nodeKind = "service"
+	nodeConfig      nodeKind = "config"
+	nodePVC         nodeKind = "pvc"
+	nodeIPBlock     nodeKind = "ipblock"
+	nodeAddress     nodeKind = "address"  // Endpoint without a pod
+	nodeExternal    nodeKind = "external" // The internet or an ExternalName target
+	nodePDB         nodeKind = "pdb"      // Only PDBs that select no workload are drawn
+)
+
+// nodeKinds lists every node kind, in legend order.
+var nodeKinds = []nodeKind{nodeIngress, nodeGateway, nodeRoute, nodeDeployment, nodeStatefulSet, nodeDaemonSet, nodeCronJob, nodeJob, nodeReplicaSet, nodePod, nodeAutoscaler, nodeHost, nodeService, nodeConfig, nodePVC, nodeIPBlock, nodeAddress, nodeExternal, nodePDB}
+
+// nodeFill returns the fill color of a node kind, matching the D2 styles.
+func nodeFill(kind nodeKind) string {
+	switch kind {
+	case nodeIngress:
+		return "#e8f5e9"
+	case nodeGateway:
+		return "#c8e6c9"
+	case nodeRoute:
+		return "#f1f8e9"
+	case nodeReplicaSet:
+		return "#eeeeee"
+	case nodePod:
+		return "#fff8e1"
+	case nodeAutoscaler:
+		return "#ede7f6"
+	case nodeHost:
+		return "#e0f2f1"
+	case nodeService:
+		return "#cce5ff"
+	case nodeConfig:
+		return "#ffffcc"
+	case nodePVC:
+		return "#e6f3ff"
+	case nodeIPBlock:
+		return "#fce4ec"
+	case nodeAddress:
+		return "#eceff1"
+	case nodeExternal:
+		return "#fff3e0"
+	case nodePDB:
+		return "#ffebee"
+	default:
+		return "#f9f9f9"
+	}
+}
+
+// namespaceFill is the fill color of namespace groups.
+const namespaceFill = "#f0f0f0"
+
+// Colors of nodes flagged with a warning, such as workloads one node or
+// zone failure would take down.
+const (
+	warnFill   = "#ffe4b3"
+	warnStroke = "#e69500"
+)
+
+// brokenStroke is the color of broken edges, such as a service with no
+// ready endpoints.
+const brokenStroke = "#d32f2f"
+
+// graph is a format-neutral view of a cluster: one group per namespace
+// holding its nodes, and edges between globally unique node IDs. Formats
+// without D2's nested containers (Mermaid, DOT, PlantUML) render from it.
+type graph struct {
+	groups []graphGroup
+	edges  []graphEdge
+}
+
+type graphGroup struct {
+	id    string
+	label string
+	nodes []graphNode
+}
+
+type graphNode struct {
+	id    string
+	kind  nodeKind
+	lines []string
+	warn  bool // Drawn with the warning colors instead of the kind's
+}
+
+// fill returns the fill color of a node.
+func (n graphNode) fill() string {
+	if n.warn {
+		return warnFill
+	}
+	return nodeFill(n.kind)
+}
+
+type graphEdge struct {
+	from   string
+	to     string
+	lines  []string
+	dashed bool // Allowed traffic rather than a configured relationship
+	broken bool // Drawn in brokenStroke
+}
+
+// buildGraph lays out a cluster using the same nodes, labels and edges as
+// the D2 renderer.
+func buildGraph(cluster *model.Cluster) *graph {
+	g := &graph{}
+
+	for _, ns := range cluster.Namespaces {
+		group := graphGroup{id: SanitizeID(ns.Name), label: ns.Name}
+
+		for _, ing := range ns.Ingresses {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindIngress, Namespace: ns.Name, Name: ing.Name}),
+				kind:  nodeIngress,
+				lines: ingressLabelLines(&ing),
+			})
+		}
+
+		for _, gw := range ns.Gateways {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindGateway, Namespace: ns.Name, Name: gw.Name}),
+				kind:  nodeGateway,
+				lines: gatewayLabelLines(&gw),
+			})
+		}
+
+		for _, route := range ns.Routes {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: route.Kind, Namespace: ns.Name, Name: route.Name}),
+				kind:  nodeRoute,
+				lines: routeLabelLines(&route, ns.Name),
+			})
+		}
+
+		for _, w := range ns.AllWorkloads() {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: w.Kind, Namespace: ns.Name, Name: w.Name}),
+				kind:  workloadNodeKind(w.Kind),
+				lines: workloadLabelLines(&ns, &w),
+			})
+			for _, rs := range w.ReplicaSets {
+				group.nodes = append(group.nodes, graphNode{
+					id:    graphNodeID(model.Ref{Kind: model.KindReplicaSet, Namespace: ns.Name, Name: rs.Name}),
+					kind:  nodeReplicaSet,
+					lines: replicaSetLabelLines(&rs),
+				})
+			}
+			for _, pod := range w.AllPods() {
+				group.nodes = append(group.nodes, graphNode{
+					id:    graphNodeID(model.Ref{Kind: model.KindPod, Namespace: ns.Name, Name: pod.Name}),
+					kind:  nodePod,
+					lines: podLabelLines(&pod),
+				})
+			}
+			if a := w.Autoscaler; a != nil {
+				group.nodes = append(group.nodes, graphNode{
+					id:    graphNodeID(model.Ref{Kind: a.Kind, Namespace: ns.Name, Name: a.Name}),
+					kind:  nodeAutoscaler,
+					lines: autoscalerLabelLines(a),
+				})
+			}
+		}
+
+		for _, svc := range ns.Services {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: svc.Name}),
+				kind:  nodeService,
+				lines: serviceLabelLines(&svc),
+				warn:  serviceUnready(&svc),
+			})
+		}
+
+		for _, name := range missingServices(&ns) {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindService, Namespace: ns.Name, Name: name}),
+				kind:  nodeService,
+				lines: missingServiceLabelLines(name),
+				warn:  true,
+			})
+		}
+
+		for _, target := range endpointNodes(&ns) {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: target.Kind, Namespace: ns.Name, Name: target.Name}),
+				kind:  endpointNodeKind(target.Kind),
+				lines: endpointLabelLines(&target),
+			})
+		}
+
+		for _, ref := range externalNodes(&ns) {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(ref),
+				kind:  nodeExternal,
+				lines: externalLabelLines(ref),
+			})
+		}
+
+		if ns.ConfigMaps > 0 || ns.Secrets > 0 {
+			group.nodes = append(group.nodes, graphNode{
+				id:    group.id + "__config",
+				kind:  nodeConfig,
+				lines: []string{fmt.Sprintf("CM: %d | Sec: %d", ns.ConfigMaps, ns.Secrets)},
+			})
+		}
+
+		for _, ref := range ns.ConfigObjects() {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: ref.Kind, Namespace: ns.Name, Name: ref.Name}),
+				kind:  nodeConfig,
+				lines: configLabelLines(&ref),
+				warn:  ref.Missing,
+			})
+		}
+
+		for _, pvc := range ns.PVCs {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindPVC, Namespace: ns.Name, Name: pvc.Name}),
+				kind:  nodePVC,
+				lines: pvcLabelLines(&pvc),
+			})
+		}
+
+		for _, block := range ns.IPBlocks() {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindIPBlock, Namespace: ns.Name, Name: block.CIDR}),
+				kind:  nodeIPBlock,
+				lines: ipBlockLabelLines(&block),
+			})
+		}
+
+		for _, pdb := range orphanPDBs(&ns) {
+			group.nodes = append(group.nodes, graphNode{
+				id:    graphNodeID(model.Ref{Kind: model.KindPDB, Namespace: ns.Name, Name: pdb.Name}),
+				kind:  nodePDB,
+				lines: orphanPDBLabelLines(&pdb),
+				warn:  true,
+			})
+		}
+
+		g.addEdges(NamespaceEdges(&ns))
+		g.groups = append(g.groups, group)
+	}
+
+	g.addEdges(CrossNamespaceEdges(cluster))
+	return g
+}
+
+func (g *graph) addEdges(edges []model.Edge) {
+	for _, e := range edges {
+		g.edges = append(g.edges, graphEdge{
+			from:   graphNodeID(e.From),
+			to:     graphNodeID(e.To),
+			lines:  labelLines(e.Label),
+			dashed: e.Type == model.EdgeAllows,
+			broken: e.Broken,
+		})
+	}
+}
+
+// graphNodeID returns a cluster-wide unique node ID. Unlike D2, the graph
+// formats have a flat ID namespace, so the namespace is part of the ID.
+func graphNodeID(ref model.Ref) string {
+	return SanitizeID(ref.Namespace) + "__" + NodeID(ref)
+}
+
+func workloadNodeKind(kind string) nodeKind {
+	switch kind {
+	case model.KindStatefulSet:
+		return nodeStatefulSet
+	case model.KindDaemonSet:
+		return nodeDaemonSet
+	case model.KindCronJob:
+		return nodeCronJob
+	case model.KindJob:
+		return nodeJob
+	default:
+		return nodeDeployment
+	}
+}
+
+// workloadLabelLines shows a workload's icon, name and replicas (with the
+// autoscaling range, as "3 → 2..10"), the run state of batch workloads and
+// the disruption budgets covering it, and flags workloads that network
+// policies leave isolated or completely open.
+func workloadLabelLines(ns *model.Namespace, w *model.Workload) []string {
+	replicas := fmt.Sprintf("%d", w.Replicas)
+	if a := w.Autoscaler; a != nil {
+		replicas = fmt.Sprintf("%d → %d..%d", w.Replicas, a.MinReplicas, a.MaxReplicas)
+	}
+	lines := []string{fmt.Sprintf("%s %s (%s)", WorkloadIcon(w.Kind), w.Name, replicas)}
+	if w.Batch != nil {
+		lines = append(lines, batchLabelLines(w.Batch)...)
+	}
+	lines = append(lines, pdbLabelLines(ns, w)...)
+	switch w.NetworkAccess {
+	case model.AccessIsolated:
+		lines = append(lines, "⛔ isolated")
+	case model.AccessOpen:
+		lines = append(lines, "⚠ open")
+	}
+	return lines
+}
+
+// pdbLabelLines lists the budgets of the PDBs selecting a workload, with a
+// warning when they allow no voluntary disruption, which blocks node drains.
+func pdbLabelLines(ns *model.Namespace, w *model.Workload) []string {
+	var lines []string
+	blocked := false
+	for _, pdb := range ns.PDBs {
+		if !pdb.Selects(w) {
+			continue
+		}
+		lines = append(lines, "🛑 "+pdb.Budget())
+		blocked = blocked || pdb.Blocks(w.Replicas)
+	}
+	if blocked {
+		lines = append(lines, "⚠ no disruptions allowed")
+	}
+	return lines
+}
+
+// orphanPDBs returns the PDBs of a namespace that select no workload. They
+// protect nothing, usually because of a selector typo.
+func orphanPDBs(ns *model.Namespace) []model.PDB {
+	var orphans []model.PDB
+	workloads := ns.AllWorkloads()
+	for _, pdb := range ns.PDBs {
+		if !slices.ContainsFunc(workloads, func(w model.Workload) bool { return pdb.Selects(&w) }) {
+			orphans = append(orphans, pdb)
+		}
+	}
+	return orphans
+}
+
+// orphanPDBLabelLines shows a PDB that selects no workload.
+func orphanPDBLabelLines(pdb *model.PDB) []string {
+	return []string{"🛑 " + pdb.Name, pdb.Budget(), "⚠ matches no workload"}
+}
+
+// batchLabelLines shows a CronJob's schedule, whether it is suspended, how
+// its last run went and how many runs are active.
+func batchLabelLines(batch *model.Batch) []string {
+	var lines []string
+	if batch.Schedule != "" {
+		lines = append(lines, "⏱ "+batch.Schedule)
+	}
+	if batch.Suspend {
+		lines = append(lines, "⏸ suspended")
+	}
+	switch batch.LastRun {
+	case model.RunSucceeded:
+		lines = append(lines, "✓ last run succeeded")
+	case model.RunFailed:
+		lines = append(lines, "✗ last run failed")
+	}
+	if batch.Active > 0 {
+		lines = append(lines, fmt.Sprintf("active: %d", batch.Active))
+	}
+	return lines
+}
+
+// replicaSetLabelLines shows a ReplicaSet's name and ready replicas.
+func replicaSetLabelLines(rs *model.ReplicaSet) []string {
+	return []string{fmt.Sprintf("▤ %s (%d/%d ready)", rs.Name, rs.Ready, rs.Replicas)}
+}
+
+// podLabelLines shows a pod's name, phase, restarts and node.
+func podLabelLines(pod *model.Pod) []string {
+	lines := []string{
+		"▢ " + pod.Name,
+		fmt.Sprintf("%s, %d restarts", pod.Phase, pod.Restarts),
+	}
+	if pod.Node != "" {
+		lines = append(lines, "node: "+pod.Node)
+	}
+	return lines
+}
+
+// autoscalerLabelLines shows an autoscaler's name and kind, and the metrics
+// it scales on.
+func autoscalerLabelLines(a *model.Autoscaler) []string {
+	kind := a.Kind
+	if kind == model.KindHPA {
+		kind = "HPA"
+	}
+	lines := []string{"⇅ " + a.Name, fmt.Sprintf("[%s]", kind)}
+	return append(lines, a.Metrics...)
+}
+
+// serviceLabelLines shows a service's name and type, whether it is
+// headless, the addresses assigned to a LoadBalancer service, and the
+// readiness of its endpoints when EndpointSlices were read.
+func serviceLabelLines(svc *model.Service) []string {
+	svcType := svc.Type
+	if svc.Headless {
+		svcType += " (headless)"
+	}
+	lines := []string{"⎈ " + svc.Name, svcType}
+	if svc.Type == model.ServiceLoadBalancer {
+		for _, addr := range svc.LoadBalancer {
+			lines = append(lines, "🌍 "+addr)
+		}
+		if len(svc.LoadBalancer) == 0 {
+			lines = append(lines, "⏳ pending")
+		}
+	}
+	if eps := svc.Endpoints; eps != nil {
+		lines = append(lines, model.FormatReady(eps.Ready, eps.Total))
+	}
+	return lines
+}
+
+// missingServiceLabelLines shows a service an ingress routes to that does
+// not exist.
+func missingServiceLabelLines(name string) []string {
+	return []string{"⎈ " + name, "⚠ missing"}
+}
+
+// serviceUnready reports whether a service's EndpointSlices were read and
+// none of its endpoints is ready, so it serves no traffic.
+func serviceUnready(svc *model.Service) bool {
+	return svc.Endpoints != nil && svc.Endpoints.Ready == 0
+}
+
+func endpointNodeKind(kind string) nodeKind {
+	if kind == model.KindPod {
+		return nodePod
+	}
+	return nodeAddress
+}
+
+// endpointLabelLines shows a pod without a workload or an address behind a
+// service.
+func endpointLabelLines(target *model.EndpointTarget) []string {
+	if target.Kind == model.KindPod {
+		return []string{"▢ " + target.Name}
+	}
+	return []string{"🔌 " + target.Name}
+}
+
+// externalLabelLines shows the internet or the DNS name of an ExternalName
+// service.
+func externalLabelLines(ref model.Ref) []string {
+	if ref.Kind == model.KindInternet {
+		return []string{"☁ internet"}
+	}
+	return []string{"☁ " + ref.Name, "[external]"}
+}
+
+// configLabelLines shows a ConfigMap or Secret used by workloads, flagging
+// it when it does not exist.
+func configLabelLines(ref *model.ConfigRef) []string {
+	icon := "📄"
+	if ref.Kind == model.KindSecret {
+		icon = "🔑"
+	}
+	lines := []string{fmt.Sprintf("%s %s", icon, ref.Name), fmt.Sprintf("[%s]", ref.Kind)}
+	if ref.Missing {
+		lines = append(lines, "⚠ missing")
+	}
+	return lines
+}
+
+// ipBlockLabelLines lists an IP block's CIDR and its exceptions.
+func ipBlockLabelLines(block *model.IPBlock) []string {
+	lines := []string{"🌍 " + block.CIDR}
+	for _, except := range block.Except {
+		lines = append(lines, "except "+except)
+	}
+	return lines
+}
+
+func pvcLabelLines(pvc *model.PVC) []string {
+	lines := []string{"💾 " + pvc.Name}
+	if pvc.Capacity != "" {
+		lines = append(lines, pvc.Capacity)
+	}
+	if pvc.StorageClass != "" {
+		lines = append(lines, fmt.Sprintf("[%s]", pvc.StorageClass))
+	}
+	return lines
+}
+
+// ingressLabelLines lists an ingress's name, hosts, class and TLS secrets.
+func ingressLabelLines(ing *model.Ingress) []string {
+	lines := []string{"🌐 " + ing.Name}
+	lines = append(lines, ing.Hosts...)
+	if ing.Class != "" {
+		lines = append(lines, fmt.Sprintf("[%s]", ing.Class))
+	}
+	for _, secret := range ing.TLSSecrets {
+		lines = append(lines, "🔒 "+secret)
+	}
+	return lines
+}
+
+// gatewayLabelLines lists a gateway's name, class and listeners.
+func gatewayLabelLines(gw *model.Gateway) []string {
+	lines := []string{"🚪 " + gw.Name}
+	if gw.Class != "" {
+		lines = append(lines, fmt.Sprintf("[%s]", gw.Class))
+	}
+	for _, l := range gw.Listeners {
+		listener := fmt.Sprintf("%s: %s/%d", l.Name, l.Protocol, l.Port)
+		if l.Hostname != "" {
+			listener += " " + l.Hostname
+		}
+		lines = append(lines, listener)
+	}
+	return lines
+}
+
+// routeLabelLines lists a route's name, kind and hostnames, followed by the
+// backends in other namespaces, which are only drawn as edges when their
+// namespace is in the diagram. Backends that no ReferenceGrant allows are
+// marked as denied.
+func routeLabelLines(route *model.Route, namespace string) []string {
+	lines := []string{"↪ " + route.Name, fmt.Sprintf("[%s]", route.Kind)}
+	lines = append(lines, route.Hostnames...)
+	for _, backend := range route.Backends {
+		if backend.Namespace == namespace {
+			continue
+		}
+		target := backend.Namespace + "/" + backend.Service
+		if backend.Denied {
+			lines = append(lines, fmt.Sprintf("✗ %s (no ReferenceGrant)", target))
+			continue
+		}
+		lines = append(lines, "→ "+target)
+	}
+	return lines
+}
+
+func labelLines(label string) []string {
+	if label == "" {
+		return nil
+	}
+	return strings.Split(label, "\n")
+}
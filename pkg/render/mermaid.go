@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// MermaidRenderer renders a cluster as a Mermaid flowchart, which GitHub and
+// GitLab display natively in markdown.
+type MermaidRenderer struct {
+	w    io.Writer
+	view View
+}
+
+func NewMermaidRenderer(w io.Writer) *MermaidRenderer {
+	return &MermaidRenderer{w: w}
+}
+
+func (r *MermaidRenderer) Render(cluster *model.Cluster) error {
+	if cluster == nil {
+		return fmt.Errorf("cluster is nil, cannot render")
+	}
+
+	g := buildViewGraph(cluster, r.view)
+	var b strings.Builder
+
+	b.WriteString("%% Generated by k8s-d2\n")
+	b.WriteString("flowchart LR\n")
+
+	for _, group := range g.groups {
+		fmt.Fprintf(&b, "  subgraph ns_%s[\"%s\"]\n", group.id, mermaidText(group.label))
+		b.WriteString("    direction LR\n")
+		for _, n := range group.nodes {
+			class := string(n.kind)
+			if n.warn {
+				class = mermaidWarnClass
+			}
+			fmt.Fprintf(&b, "    %s[\"%s\"]:::%s\n", n.id, mermaidLines(n.lines), class)
+		}
+		b.WriteString("  end\n")
+		fmt.Fprintf(&b, "  style ns_%s fill:%s\n", group.id, namespaceFill)
+	}
+
+	var broken []string
+	for i, e := range g.edges {
+		if e.broken {
+			broken = append(broken, strconv.Itoa(i))
+		}
+		arrow := "-->"
+		if e.dashed {
+			arrow = "-.->"
+		}
+		if len(e.lines) == 0 {
+			fmt.Fprintf(&b, "  %s %s %s\n", e.from, arrow, e.to)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", e.from, arrow, mermaidLines(e.lines), e.to)
+	}
+
+	for _, kind := range nodeKinds {
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#333\n", kind, nodeFill(kind))
+	}
+	fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", mermaidWarnClass, warnFill, warnStroke)
+	if len(broken) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:%s,stroke-width:2px\n", strings.Join(broken, ","), brokenStroke)
+	}
+
+	if _, err := fmt.Fprint(r.w, b.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// mermaidWarnClass is the class of nodes flagged with a warning.
+const mermaidWarnClass = "warning"
+
+// mermaidLines joins label lines with Mermaid's HTML line break.
+func mermaidLines(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = mermaidText(line)
+	}
+	return strings.Join(escaped, "<br/>")
+}
+
+// mermaidText escapes characters that would end a quoted Mermaid label.
+func mermaidText(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
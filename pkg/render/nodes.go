@@ -0,0 +1,226 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// unscheduledGroup holds the workloads of pods no node was assigned to.
+const unscheduledGroup = "unscheduled"
+
+// buildViewGraph lays out a cluster grouped by namespace, node or zone.
+func buildViewGraph(cluster *model.Cluster, view View) *graph {
+	switch view {
+	case ViewNodes:
+		return buildNodeGraph(cluster)
+	case ViewZones:
+		return buildZoneGraph(cluster)
+	default:
+		return buildGraph(cluster)
+	}
+}
+
+// placement is the part of a workload running in one node or zone.
+type placement struct {
+	namespace string
+	workload  *model.Workload
+	pods      int      // Pods in the node or zone
+	total     int      // Pods of the workload
+	risks     []string // Availability warnings, see availabilityRisks
+}
+
+// placeWorkloads splits every workload by where its pods run. place maps a
+// pod's node name to the group it belongs to; pods without a node map from
+// "". Groups are returned in order of first appearance.
+func placeWorkloads(cluster *model.Cluster, place func(node string) string) (map[string][]placement, []string) {
+	zoneOf := nodeZones(cluster)
+	placements := make(map[string][]placement)
+	var groups []string
+	for i := range cluster.Namespaces {
+		ns := &cluster.Namespaces[i]
+		workloads := ns.AllWorkloads()
+		for j := range workloads {
+			w := &workloads[j]
+			pods := w.AllPods()
+			risks := availabilityRisks(w, pods, zoneOf)
+			var keys []string
+			counts := make(map[string]int)
+			for _, pod := range pods {
+				key := place(pod.Node)
+				if _, ok := counts[key]; !ok {
+					keys = append(keys, key)
+				}
+				counts[key]++
+			}
+			for _, key := range keys {
+				if _, ok := placements[key]; !ok {
+					groups = append(groups, key)
+				}
+				placements[key] = append(placements[key], placement{
+					namespace: ns.Name,
+					workload:  w,
+					pods:      counts[key],
+					total:     len(pods),
+					risks:     risks,
+				})
+			}
+		}
+	}
+	return placements, groups
+}
+
+// buildNodeGraph lays out a cluster with one group per node, holding a
+// card with the node's zone, capacity and taints, and every workload with
+// pods scheduled on it. Workloads of pods without a node go in a last
+// "unscheduled" group. Relationships are left out: the view answers where
+// things run, not how they talk.
+func buildNodeGraph(cluster *model.Cluster) *graph {
+	placements, podNodes := placeWorkloads(cluster, func(node string) string { return node })
+
+	g := &graph{}
+	known := make(map[string]bool)
+	for _, node := range cluster.Nodes {
+		known[node.Name] = true
+		group := graphGroup{id: nodeGroupID(node.Name), label: nodeTitle(&node)}
+		group.nodes = append(group.nodes, graphNode{
+			id:    group.id + "__info",
+			kind:  nodeHost,
+			lines: nodeInfoLines(&node),
+		})
+		group.nodes = append(group.nodes, placementNodes(group.id, placements[node.Name], false)...)
+		g.groups = append(g.groups, group)
+	}
+
+	// Pods can name nodes that were not collected, e.g. from a snapshot
+	var unknown []string
+	for _, node := range podNodes {
+		if node != "" && !known[node] {
+			unknown = append(unknown, node)
+		}
+	}
+	slices.Sort(unknown)
+	for _, node := range unknown {
+		group := graphGroup{id: nodeGroupID(node), label: "🖥 " + node}
+		group.nodes = placementNodes(group.id, placements[node], false)
+		g.groups = append(g.groups, group)
+	}
+
+	g.groups = appendUnscheduled(g.groups, placements[""])
+	return g
+}
+
+// appendUnscheduled adds the group of workloads with unscheduled pods, if
+// there are any.
+func appendUnscheduled(groups []graphGroup, pending []placement) []graphGroup {
+	if len(pending) == 0 {
+		return groups
+	}
+	group := graphGroup{id: unscheduledGroup, label: "⏳ " + unscheduledGroup}
+	group.nodes = placementNodes(group.id, pending, false)
+	return append(groups, group)
+}
+
+// placementNodes returns the nodes of the workloads placed in a group,
+// labelled with their pods there: "(2)", or "(2/3)" with shares, which
+// tells what a zone takes down with it. Workloads at risk are flagged.
+func placementNodes(groupID string, placements []placement, shares bool) []graphNode {
+	nodes := make([]graphNode, 0, len(placements))
+	for _, p := range placements {
+		ref := model.Ref{Kind: p.workload.Kind, Namespace: p.namespace, Name: p.workload.Name}
+		count := fmt.Sprintf("%d", p.pods)
+		if shares {
+			count = fmt.Sprintf("%d/%d", p.pods, p.total)
+		}
+		lines := []string{
+			fmt.Sprintf("%s %s (%s)", WorkloadIcon(p.workload.Kind), p.workload.Name, count),
+			p.namespace,
+		}
+		nodes = append(nodes, graphNode{
+			id:    groupID + "__" + SanitizeID(p.namespace) + "__" + NodeID(ref),
+			kind:  workloadNodeKind(p.workload.Kind),
+			lines: append(lines, p.risks...),
+			warn:  len(p.risks) > 0,
+		})
+	}
+	return nodes
+}
+
+// nodeGroupID returns the identifier of a node group.
+func nodeGroupID(name string) string {
+	return "node_" + SanitizeID(name)
+}
+
+// nodeTitle names a node and its zone.
+func nodeTitle(node *model.Node) string {
+	if node.Zone == "" {
+		return "🖥 " + node.Name
+	}
+	return fmt.Sprintf("🖥 %s (%s)", node.Name, node.Zone)
+}
+
+// nodeInfoLines lists a node's region, capacity and taints.
+func nodeInfoLines(node *model.Node) []string {
+	var lines []string
+	if node.Region != "" {
+		lines = append(lines, "region: "+node.Region)
+	}
+	var capacity []string
+	for _, c := range []struct{ name, value string }{{"cpu", node.CPU}, {"memory", node.Memory}, {"pods", node.Pods}} {
+		if c.value != "" {
+			capacity = append(capacity, c.name+": "+c.value)
+		}
+	}
+	if len(capacity) > 0 {
+		lines = append(lines, strings.Join(capacity, ", "))
+	}
+	for _, t := range node.Taints {
+		lines = append(lines, "taint: "+model.FormatTaint(t))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no capacity reported")
+	}
+	return lines
+}
+
+// renderPlacementView writes the node or zone view: one container per
+// group with the same content as the other formats, laid out in the
+// configured grid.
+func (r *D2Renderer) renderPlacementView(cluster *model.Cluster) error {
+	g := buildViewGraph(cluster, r.view)
+	var b strings.Builder
+
+	indent := ""
+	if r.gridColumns > 0 {
+		fmt.Fprintf(&b, "%s: {\n  grid-columns: %d\n\n", r.view, r.gridColumns)
+		indent = "  "
+	}
+
+	for _, group := range g.groups {
+		fmt.Fprintf(&b, "%s%s: {\n", indent, group.id)
+		fmt.Fprintf(&b, "%s  label: \"%s\"\n", indent, EscapeD2(group.label))
+		fmt.Fprintf(&b, "%s  style.fill: \"%s\"\n\n", indent, namespaceFill)
+		for _, n := range group.nodes {
+			fmt.Fprintf(&b, "%s  %s: {\n", indent, n.id)
+			fmt.Fprintf(&b, "%s    label: \"%s\"\n", indent, EscapeD2(strings.Join(n.lines, "\n")))
+			fmt.Fprintf(&b, "%s    style.fill: \"%s\"\n", indent, n.fill())
+			if n.warn {
+				fmt.Fprintf(&b, "%s    style.stroke: \"%s\"\n", indent, warnStroke)
+				fmt.Fprintf(&b, "%s    style.stroke-width: 2\n", indent)
+			}
+			fmt.Fprintf(&b, "%s  }\n", indent)
+		}
+		fmt.Fprintf(&b, "%s}\n\n", indent)
+	}
+
+	if r.gridColumns > 0 {
+		b.WriteString("}\n")
+	}
+
+	if _, err := fmt.Fprint(r.w, b.String()); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// PlantUMLRenderer renders a cluster as a PlantUML deployment diagram with
+// one package per namespace.
+type PlantUMLRenderer struct {
+	w    io.Writer
+	view View
+}
+
+func NewPlantUMLRenderer(w io.Writer) *PlantUMLRenderer {
+	return &PlantUMLRenderer{w: w}
+}
+
+func (r *PlantUMLRenderer) Render(cluster *model.Cluster) error {
+	if cluster == nil {
+		return fmt.Errorf("cluster is nil, cannot render")
+	}
+
+	g := buildViewGraph(cluster, r.view)
+	var b strings.Builder
+
+	b.WriteString("@startuml\n")
+	b.WriteString("' Generated by k8s-d2\n")
+	b.WriteString("left to right direction\n\n")
+
+	for _, group := range g.groups {
+		fmt.Fprintf(&b, "package %s as ns_%s %s {\n", plantUMLQuote(group.label), group.id, namespaceFill)
+		for _, n := range group.nodes {
+			color := n.fill()
+			if n.warn {
+				color += ";line:" + strings.TrimPrefix(warnStroke, "#")
+			}
+			fmt.Fprintf(&b, "  %s %s as %s %s\n",
+				plantUMLElement(n.kind), plantUMLQuote(strings.Join(n.lines, "\n")), n.id, color)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for _, e := range g.edges {
+		arrow := "-->"
+		if e.dashed {
+			arrow = "..>"
+		}
+		if e.broken {
+			arrow = "-[" + brokenStroke + ",bold]->"
+		}
+		if len(e.lines) == 0 {
+			fmt.Fprintf(&b, "%s %s %s\n", e.from, arrow, e.to)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s %s : %s\n", e.from, arrow, e.to, plantUMLText(strings.Join(e.lines, "\n")))
+	}
+
+	b.WriteString("@enduml\n")
+
+	if _, err := fmt.Fprint(r.w, b.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// plantUMLElement returns the deployment diagram element for a node kind.
+func plantUMLElement(kind nodeKind) string {
+	switch kind {
+	case nodeIngress:
+		return "hexagon"
+	case nodeGateway:
+		return "stack"
+	case nodeRoute:
+		return "queue"
+	case nodeStatefulSet:
+		return "collections"
+	case nodeDaemonSet:
+		return "node"
+	case nodeCronJob:
+		return "control"
+	case nodeJob:
+		return "agent"
+	case nodeReplicaSet:
+		return "frame"
+	case nodePod:
+		return "artifact"
+	case nodeAutoscaler:
+		return "usecase"
+	case nodeHost:
+		return "node"
+	case nodeService:
+		return "boundary"
+	case nodeConfig:
+		return "card"
+	case nodePVC:
+		return "database"
+	case nodeIPBlock:
+		return "cloud"
+	case nodeAddress:
+		return "interface"
+	case nodeExternal:
+		return "entity"
+	case nodePDB:
+		return "file"
+	default:
+		return "rectangle"
+	}
+}
+
+// plantUMLQuote returns s as a quoted PlantUML display name.
+func plantUMLQuote(s string) string {
+	return `"` + plantUMLText(s) + `"`
+}
+
+// plantUMLText escapes newlines and replaces double quotes, which PlantUML
+// display names cannot contain.
+func plantUMLText(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
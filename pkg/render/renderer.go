@@ -0,0 +1,116 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// Renderer writes a cluster topology in a diagram or data format.
+type Renderer interface {
+	Render(cluster *model.Cluster) error
+}
+
+// Format identifies an output format.
+type Format string
+
+const (
+	FormatD2       Format = "d2"
+	FormatMermaid  Format = "mermaid"
+	FormatDOT      Format = "dot"
+	FormatPlantUML Format = "plantuml"
+	FormatJSON     Format = "json"
+	FormatYAML     Format = "yaml"
+)
+
+// Formats lists every supported output format.
+var Formats = []Format{FormatD2, FormatMermaid, FormatDOT, FormatPlantUML, FormatJSON, FormatYAML}
+
+// View selects how diagrams group resources.
+type View string
+
+const (
+	ViewNamespaces View = "namespaces" // Resources inside their namespace
+	ViewNodes      View = "nodes"      // Workloads inside the nodes their pods run on
+	ViewZones      View = "zones"      // Workloads inside the zones their pods run in
+)
+
+// Views lists every supported view.
+var Views = []View{ViewNamespaces, ViewNodes, ViewZones}
+
+// NeedsNodes reports whether a view places workloads by where their pods
+// run, so clusters must be collected with pods and nodes.
+func (v View) NeedsNodes() bool {
+	return v == ViewNodes || v == ViewZones
+}
+
+// Options configures renderers. Options that do not apply to a format are
+// ignored by its renderer.
+type Options struct {
+	GridColumns int  // Number of namespace (or node) columns (D2 only)
+	View        View // Grouping of diagram formats; empty means ViewNamespaces
+}
+
+// ParseFormat validates a format name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q (supported: %s)", s, FormatList())
+}
+
+// FormatList returns the supported formats as a comma-separated string,
+// for flag help and error messages.
+func FormatList() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseView validates a view name.
+func ParseView(s string) (View, error) {
+	for _, v := range Views {
+		if string(v) == strings.ToLower(s) {
+			return v, nil
+		}
+	}
+	names := make([]string, len(Views))
+	for i, v := range Views {
+		names[i] = string(v)
+	}
+	return "", fmt.Errorf("unknown view %q (supported: %s)", s, strings.Join(names, ", "))
+}
+
+// New creates the renderer for a format writing to w.
+func New(format Format, w io.Writer, opts Options) (Renderer, error) {
+	switch format {
+	case FormatD2:
+		r := NewD2Renderer(w, opts.GridColumns)
+		r.view = opts.View
+		return r, nil
+	case FormatMermaid:
+		r := NewMermaidRenderer(w)
+		r.view = opts.View
+		return r, nil
+	case FormatDOT:
+		r := NewDOTRenderer(w)
+		r.view = opts.View
+		return r, nil
+	case FormatPlantUML:
+		r := NewPlantUMLRenderer(w)
+		r.view = opts.View
+		return r, nil
+	case FormatJSON:
+		return NewJSONRenderer(w), nil
+	case FormatYAML:
+		return NewYAMLRenderer(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (supported: %s)", format, FormatList())
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"slices"
+
+	"github.com/vieitesss/k8s-d2/pkg/model"
+)
+
+// noZoneGroup holds the workloads of nodes without a zone label, or that
+// were not collected.
+const noZoneGroup = "no zone"
+
+// buildZoneGraph lays out a cluster with one group per availability zone,
+// holding a card listing the zone's nodes and every workload with pods in
+// it, as "(pods in zone/pods)": a workload at 3/3 goes down with the zone.
+// Nodes without a zone label go in a "no zone" group and workloads of pods
+// without a node in a last "unscheduled" group.
+func buildZoneGraph(cluster *model.Cluster) *graph {
+	zoneOf := nodeZones(cluster)
+	placements, _ := placeWorkloads(cluster, func(node string) string {
+		if node == "" {
+			return ""
+		}
+		if zone := zoneOf[node]; zone != "" {
+			return zone
+		}
+		return noZoneGroup
+	})
+
+	hosts := make(map[string][]string)
+	for _, node := range cluster.Nodes {
+		zone := node.Zone
+		if zone == "" {
+			zone = noZoneGroup
+		}
+		hosts[zone] = append(hosts[zone], node.Name)
+	}
+
+	var zones []string
+	for zone := range hosts {
+		if zone != noZoneGroup {
+			zones = append(zones, zone)
+		}
+	}
+	for zone := range placements {
+		if zone != "" && zone != noZoneGroup && hosts[zone] == nil {
+			zones = append(zones, zone)
+		}
+	}
+	slices.Sort(zones)
+	if hosts[noZoneGroup] != nil || placements[noZoneGroup] != nil {
+		zones = append(zones, noZoneGroup)
+	}
+
+	g := &graph{}
+	for _, zone := range zones {
+		group := graphGroup{id: zoneGroupID(zone), label: "📍 " + zone}
+		if names := hosts[zone]; len(names) > 0 {
+			lines := make([]string, len(names))
+			for i, name := range names {
+				lines[i] = "🖥 " + name
+			}
+			group.nodes = append(group.nodes, graphNode{id: group.id + "__info", kind: nodeHost, lines: lines})
+		}
+		group.nodes = append(group.nodes, placementNodes(group.id, placements[zone], true)...)
+		g.groups = append(g.groups, group)
+	}
+
+	g.groups = appendUnscheduled(g.groups, placements[""])
+	return g
+}
+
+// zoneGroupID returns the identifier of a zone group.
+func zoneGroupID(zone string) string {
+	return "zone_" + SanitizeID(zone)
+}
+
+// nodeZones maps node names to their zone.
+func nodeZones(cluster *model.Cluster) map[string]string {
+	zones := make(map[string]string, len(cluster.Nodes))
+	for _, node := range cluster.Nodes {
+		zones[node.Name] = node.Zone
+	}
+	return zones
+}
+
+// availabilityRisks lists why a single failure could take down a workload:
+// every scheduled replica on one node or in one zone, or nothing asking
+// the scheduler to spread them. Only Deployments and StatefulSets with
+// more than one replica are checked; DaemonSets and Jobs are not meant to
+// be spread, and a single replica always lives in one place.
+func availabilityRisks(w *model.Workload, pods []model.Pod, zoneOf map[string]string) []string {
+	if (w.Kind != model.KindDeployment && w.Kind != model.KindStatefulSet) || w.Replicas < 2 {
+		return nil
+	}
+
+	nodes := make(map[string]bool)
+	zones := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Node == "" {
+			continue
+		}
+		nodes[pod.Node] = true
+		zones[zoneOf[pod.Node]] = true
+	}
+
+	var risks []string
+	switch {
+	case len(nodes) == 1:
+		risks = append(risks, "⚠ all replicas on one node")
+	case len(zones) == 1 && !zones[""]:
+		risks = append(risks, "⚠ all replicas in one zone")
+	}
+	if !w.Spread {
+		risks = append(risks, "⚠ no spread constraints")
+	}
+	return risks
+}
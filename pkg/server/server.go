@@ -0,0 +1,268 @@
+// Package server serves the cluster topology over HTTP as D2 source, JSON
+// and an auto-refreshing HTML page.
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/vieitesss/k8s-d2/pkg/model"
+	"github.com/vieitesss/k8s-d2/pkg/render"
+)
+
+// DefaultPageRefresh is how often the HTML page reloads itself.
+const DefaultPageRefresh = 10 * time.Second
+
+// DefaultD2Command is the d2 CLI that renders the page's SVG.
+const DefaultD2Command = "d2"
+
+type Options struct {
+	GridColumns int
+	View        render.View
+	PageRefresh time.Duration
+	D2Command   string // d2 CLI for /diagram.svg; DefaultD2Command when empty
+}
+
+// errNoD2 is returned for /diagram.svg when the d2 CLI is not installed.
+var errNoD2 = errors.New("d2 is not installed; see /diagram.d2 for the source")
+
+// Server caches the latest topology and renders it on request. The cache is
+// filled through Update, typically from a kube.Watcher or Poll.
+type Server struct {
+	opts Options
+
+	mu      sync.RWMutex
+	cluster *model.Cluster
+	updated time.Time
+
+	// The SVG of the topology cached at svgUpdated, rendered once per update
+	svgMu      sync.Mutex
+	svg        []byte
+	svgUpdated time.Time
+}
+
+func New(opts Options) *Server {
+	if opts.PageRefresh <= 0 {
+		opts.PageRefresh = DefaultPageRefresh
+	}
+	if opts.D2Command == "" {
+		opts.D2Command = DefaultD2Command
+	}
+	return &Server{opts: opts}
+}
+
+// Update replaces the cached topology.
+func (s *Server) Update(cluster *model.Cluster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cluster = cluster
+	s.updated = time.Now()
+}
+
+func (s *Server) current() (*model.Cluster, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cluster, s.updated
+}
+
+// Poll refreshes the cache with fetch every interval until ctx is done.
+// Failed refreshes are logged and keep the previous topology.
+func (s *Server) Poll(ctx context.Context, interval time.Duration, fetch func(context.Context) (*model.Cluster, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cluster, err := fetch(ctx)
+			if err != nil {
+				log.Warn("Failed to refresh topology", "err", err)
+				continue
+			}
+			s.Update(cluster)
+		}
+	}
+}
+
+// Handler returns the HTTP routes:
+//
+//	/              HTML page showing /diagram.svg
+//	/diagram.svg   SVG rendered from the D2 source by the d2 CLI
+//	/diagram.d2    D2 source
+//	/topology.json versioned JSON topology
+//	/healthz       200 once a topology is cached, 503 before
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /diagram.svg", s.handleSVG)
+	mux.HandleFunc("GET /diagram.d2", s.handleFormat(render.FormatD2, "text/plain; charset=utf-8"))
+	mux.HandleFunc("GET /topology.json", s.handleFormat(render.FormatJSON, "application/json"))
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+func (s *Server) handleFormat(format render.Format, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cluster, _ := s.current()
+		if cluster == nil {
+			http.Error(w, "topology not loaded yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		data, err := s.render(cluster, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(data)
+	}
+}
+
+// handleSVG serves the diagram laid out by the d2 CLI, the same one as
+// /diagram.d2. Rendering in the browser instead would need a JavaScript
+// library from a CDN, which in-cluster dashboards often cannot reach, and
+// another diagram language.
+func (s *Server) handleSVG(w http.ResponseWriter, r *http.Request) {
+	cluster, updated := s.current()
+	if cluster == nil {
+		http.Error(w, "topology not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	svg, err := s.renderSVG(r.Context(), cluster, updated)
+	if errors.Is(err, errNoD2) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, _ = w.Write(svg)
+}
+
+// renderSVG pipes the D2 source of the topology cached at updated through
+// the d2 CLI. The result is kept until the topology changes, as layouts of
+// large clusters take seconds.
+func (s *Server) renderSVG(ctx context.Context, cluster *model.Cluster, updated time.Time) ([]byte, error) {
+	s.svgMu.Lock()
+	defer s.svgMu.Unlock()
+	if s.svg != nil && s.svgUpdated.Equal(updated) {
+		return s.svg, nil
+	}
+
+	path, err := exec.LookPath(s.opts.D2Command)
+	if err != nil {
+		return nil, errNoD2
+	}
+	source, err := s.render(cluster, render.FormatD2)
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-", "-")
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stderr = &stderr
+	svg, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("d2: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	s.svg, s.svgUpdated = svg, updated
+	return svg, nil
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	page := pageData{RefreshSeconds: int(s.opts.PageRefresh.Seconds())}
+
+	cluster, updated := s.current()
+	if cluster != nil {
+		page.Loaded = true
+		page.Updated = updated.Format(time.RFC3339)
+		_, err := exec.LookPath(s.opts.D2Command)
+		page.HasD2 = err == nil
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if cluster, _ := s.current(); cluster == nil {
+		http.Error(w, "topology not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *Server) render(cluster *model.Cluster, format render.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	renderer, err := render.New(format, &buf, render.Options{GridColumns: s.opts.GridColumns, View: s.opts.View})
+	if err != nil {
+		return nil, err
+	}
+	if err := renderer.Render(cluster); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type pageData struct {
+	RefreshSeconds int
+	Loaded         bool
+	HasD2          bool
+	Updated        string
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="{{.RefreshSeconds}}">
+  <title>k8s-d2</title>
+  <style>
+    body { font-family: sans-serif; margin: 1rem 2rem; }
+    header { display: flex; gap: 1.5rem; align-items: baseline; }
+    .muted { color: #666; font-size: 0.9rem; }
+    img { max-width: 100%; }
+  </style>
+</head>
+<body>
+  <header>
+    <h1>k8s-d2</h1>
+    <a href="diagram.d2">D2 source</a>
+    <a href="topology.json">JSON</a>
+    {{if .Updated}}<span class="muted">updated {{.Updated}}</span>{{end}}
+  </header>
+  {{if and .Loaded .HasD2}}
+  <img src="diagram.svg" alt="cluster diagram">
+  {{else if .Loaded}}
+  <p class="muted">Install the <a href="https://d2lang.com">d2</a> CLI next to k8sdd to see the diagram here.</p>
+  {{else}}
+  <p class="muted">Loading topology...</p>
+  {{end}}
+</body>
+</html>
+`))